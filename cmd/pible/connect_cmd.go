@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const connectFailuresUsage = "usage: pible connect failures [-db FILE] [-min N] [-since DURATION] [-limit N]"
+
+// runConnectCommand handles "pible connect <subcommand> ...".
+func runConnectCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, connectFailuresUsage)
+		return 2
+	}
+	switch args[0] {
+	case "failures":
+		return runConnectFailures(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown connect subcommand: %s\n", args[0])
+		return 2
+	}
+}
+
+// runConnectFailures lists devices that repeatedly fail to connect, with the
+// failure classes, and totals per class.
+func runConnectFailures(args []string) int {
+	fs := flag.NewFlagSet("connect failures", flag.ContinueOnError)
+	dbPath := fs.String("db", "bluetooth_devices.db", "SQLite database to read")
+	minFailures := fs.Int("min", 3, "Only list devices with at least this many failed attempts")
+	since := fs.Duration("since", 0, "Only count attempts newer than this (e.g. 72h; 0 = all)")
+	limit := fs.Int("limit", 50, "Maximum number of devices to list (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, connectFailuresUsage)
+		return 2
+	}
+
+	store, code := openExistingStore(*dbPath)
+	if store == nil {
+		return code
+	}
+	defer store.Close()
+
+	sinceTS := ""
+	if *since > 0 {
+		sinceTS = time.Now().Add(-*since).Format("2006-01-02 15:04:05")
+	}
+	stats, err := store.ListDeviceConnectStats(context.Background(), sinceTS)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] read connection attempts: %v\n", err)
+		return 1
+	}
+
+	var attempts, failures int
+	classTotals := map[string]int{}
+	failing := stats[:0:0]
+	for _, st := range stats {
+		attempts += st.Attempts
+		failures += st.Failures
+		for c, n := range st.ByClass {
+			classTotals[c] += n
+		}
+		if st.Failures >= *minFailures {
+			failing = append(failing, st)
+		}
+	}
+	sort.SliceStable(failing, func(i, j int) bool { return failing[i].Failures > failing[j].Failures })
+
+	for i, st := range failing {
+		if *limit > 0 && i >= *limit {
+			fmt.Printf("... %d more\n", len(failing)-*limit)
+			break
+		}
+		lastOK := st.LastSuccess
+		if lastOK == "" {
+			lastOK = "never"
+		}
+		fmt.Printf("%-17s %-24q failed %d/%d [%s] last=%s ok=%s\n", st.MAC, st.Name, st.Failures, st.Attempts,
+			classCounts(st.ByClass), st.LastAttempt, lastOK)
+		if st.LastError != "" {
+			fmt.Printf("    last error: %s\n", st.LastError)
+		}
+	}
+
+	fmt.Printf("%d devices, %d attempts, %d failed; %d device(s) with >= %d failures\n", len(stats), attempts, failures, len(failing), *minFailures)
+	if len(classTotals) > 0 {
+		fmt.Printf("failures by class: %s\n", classCounts(classTotals))
+	}
+	return 0
+}
+
+// classCounts formats class counts, most frequent first, e.g. "timeout:4 auth_required:1".
+func classCounts(m map[string]int) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s:%d", k, m[k]))
+	}
+	return strings.Join(parts, " ")
+}
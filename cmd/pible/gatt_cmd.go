@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"pible/internal/bluetooth"
+	"pible/internal/db"
+)
+
+const (
+	gattDiffUsage   = "usage: pible gatt diff [-db FILE] [-from SESSION] [-to SESSION] [-list] <mac>"
+	gattSharedUsage = "usage: pible gatt shared [-db FILE] [-min N]"
+)
+
+// runGattCommand handles "pible gatt <subcommand> ...".
+func runGattCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, gattDiffUsage)
+		fmt.Fprintln(os.Stderr, gattSharedUsage)
+		return 2
+	}
+	switch args[0] {
+	case "diff":
+		return runGattDiff(args[1:])
+	case "shared":
+		return runGattShared(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown gatt subcommand: %s\n", args[0])
+		return 2
+	}
+}
+
+// runGattDiff compares the stored GATT dumps of one device between two
+// sessions (by default the last two).
+func runGattDiff(args []string) int {
+	fs := flag.NewFlagSet("gatt diff", flag.ContinueOnError)
+	dbPath := fs.String("db", "bluetooth_devices.db", "SQLite database to read")
+	from := fs.Int64("from", 0, "Older session ID (default: the session before -to)")
+	to := fs.Int64("to", 0, "Newer session ID (default: the latest session)")
+	list := fs.Bool("list", false, "List the fingerprint of every session instead of diffing")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, gattDiffUsage)
+		return 2
+	}
+	mac := strings.ToUpper(strings.TrimSpace(fs.Arg(0)))
+
+	store, code := openExistingStore(*dbPath)
+	if store == nil {
+		return code
+	}
+	defer store.Close()
+
+	history, err := store.ListGattFingerprints(context.Background(), mac)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] read fingerprints: %v\n", err)
+		return 1
+	}
+	if len(history) == 0 {
+		fmt.Printf("no GATT fingerprints stored for %s\n", mac)
+		return 0
+	}
+
+	if *list {
+		for _, f := range history {
+			fmt.Printf("session %-5d %s %s %s%s\n", f.SessionID, f.Timestamp, bluetooth.ShortFingerprint(f.Fingerprint),
+				valueOr(f.Firmware, "-"), bracketed(f.Changes))
+		}
+		return 0
+	}
+
+	toIdx := len(history) - 1
+	if *to > 0 {
+		toIdx = fingerprintIndex(history, *to)
+		if toIdx < 0 {
+			fmt.Fprintf(os.Stderr, "[ERROR] no fingerprint for %s in session %d\n", mac, *to)
+			return 1
+		}
+	}
+	fromIdx := toIdx - 1
+	if *from > 0 {
+		fromIdx = fingerprintIndex(history, *from)
+		if fromIdx < 0 {
+			fmt.Fprintf(os.Stderr, "[ERROR] no fingerprint for %s in session %d\n", mac, *from)
+			return 1
+		}
+	}
+	if fromIdx < 0 {
+		fmt.Printf("only one GATT dump stored for %s (session %d)\n", mac, history[toIdx].SessionID)
+		return 0
+	}
+
+	a, b := history[fromIdx], history[toIdx]
+	oldAttrs, err := bluetooth.ParseGattAttributes(a.AttributesJSON)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] session %d: %v\n", a.SessionID, err)
+		return 1
+	}
+	newAttrs, err := bluetooth.ParseGattAttributes(b.AttributesJSON)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] session %d: %v\n", b.SessionID, err)
+		return 1
+	}
+
+	fmt.Printf("%s session %d (%s) -> session %d (%s)\n", mac, a.SessionID, a.Timestamp, b.SessionID, b.Timestamp)
+	if a.Fingerprint == b.Fingerprint {
+		fmt.Printf("fingerprint %s (unchanged)\n", bluetooth.ShortFingerprint(b.Fingerprint))
+	} else {
+		fmt.Printf("fingerprint %s -> %s\n", bluetooth.ShortFingerprint(a.Fingerprint), bluetooth.ShortFingerprint(b.Fingerprint))
+	}
+	oldFw, newFw := valueOr(a.Firmware, ""), valueOr(b.Firmware, "")
+	if oldFw != "" && newFw != "" && oldFw != newFw {
+		fmt.Printf("FIRMWARE CHANGED: %s -> %s\n", oldFw, newFw)
+	}
+
+	var added, removed, changed int
+	for _, c := range bluetooth.DiffGattAttributes(oldAttrs, newAttrs) {
+		switch c.Op {
+		case "+":
+			added++
+			fmt.Printf("+ %s %s\n", c.Key, attributeSummary(*c.New))
+		case "-":
+			removed++
+			fmt.Printf("- %s %s\n", c.Key, attributeSummary(*c.Old))
+		case "~":
+			changed++
+			var parts []string
+			for _, f := range c.Fields {
+				switch f {
+				case "handle":
+					parts = append(parts, fmt.Sprintf("handle %s -> %s", handleString(c.Old.Handle), handleString(c.New.Handle)))
+				case "flags":
+					parts = append(parts, fmt.Sprintf("flags [%s] -> [%s]", strings.Join(c.Old.Flags, ","), strings.Join(c.New.Flags, ",")))
+				case "value":
+					parts = append(parts, fmt.Sprintf("value %s -> %s", valueString(*c.Old), valueString(*c.New)))
+				}
+			}
+			fmt.Printf("~ %s %s\n", c.Key, strings.Join(parts, "; "))
+		}
+	}
+	fmt.Printf("%d added, %d removed, %d changed\n", added, removed, changed)
+	return 0
+}
+
+// runGattShared lists GATT fingerprints reported by several devices, which
+// usually means the same product model.
+func runGattShared(args []string) int {
+	fs := flag.NewFlagSet("gatt shared", flag.ContinueOnError)
+	dbPath := fs.String("db", "bluetooth_devices.db", "SQLite database to read")
+	minDevices := fs.Int("min", 2, "Minimum number of devices sharing a fingerprint")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, gattSharedUsage)
+		return 2
+	}
+
+	store, code := openExistingStore(*dbPath)
+	if store == nil {
+		return code
+	}
+	defer store.Close()
+
+	groups, err := store.ListSharedGattFingerprints(context.Background(), *minDevices)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] read fingerprints: %v\n", err)
+		return 1
+	}
+	for _, g := range groups {
+		fmt.Printf("%s %d devices\n", bluetooth.ShortFingerprint(g.Fingerprint), len(g.Devices))
+		for _, d := range g.Devices {
+			model := strings.TrimSpace(strings.Join([]string{d.Manufacturer, d.Model}, " "))
+			if model != "" {
+				model = " [" + model + "]"
+			}
+			fmt.Printf("    %-17s %-24q last=%s%s\n", d.MAC, d.Name, d.LastSeen, model)
+		}
+	}
+	fmt.Printf("%d shared fingerprint(s)\n", len(groups))
+	return 0
+}
+
+// openExistingStore opens a database for the offline commands without
+// creating a new file. On failure it returns a nil store and an exit code.
+func openExistingStore(path string) (*db.Store, int) {
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] database: %v\n", err)
+		return nil, 1
+	}
+	store, err := db.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] failed to open database: %v\n", err)
+		return nil, 1
+	}
+	return store, 0
+}
+
+func fingerprintIndex(history []db.GattFingerprint, sessionID int64) int {
+	for i, f := range history {
+		if f.SessionID == sessionID {
+			return i
+		}
+	}
+	return -1
+}
+
+func attributeSummary(a bluetooth.GattAttribute) string {
+	s := "handle " + handleString(a.Handle)
+	if len(a.Flags) > 0 {
+		s += " flags [" + strings.Join(a.Flags, ",") + "]"
+	}
+	if a.Value != "" {
+		s += " value " + valueString(a)
+	}
+	return s
+}
+
+func handleString(h *uint16) string {
+	if h == nil {
+		return "-"
+	}
+	return fmt.Sprintf("0x%04x", *h)
+}
+
+func valueString(a bluetooth.GattAttribute) string {
+	if a.Value == "" {
+		return "(none)"
+	}
+	if a.Text != "" {
+		return fmt.Sprintf("%s (%q)", a.Value, a.Text)
+	}
+	return a.Value
+}
+
+func valueOr(p *string, def string) string {
+	if p == nil || *p == "" {
+		return def
+	}
+	return *p
+}
+
+func bracketed(p *string) string {
+	if p == nil || *p == "" {
+		return ""
+	}
+	return " [" + *p + "]"
+}
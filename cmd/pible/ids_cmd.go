@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"pible/internal/ids"
+)
+
+// runIDsCommand handles "pible ids <subcommand> ...".
+func runIDsCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: pible ids import [-data-dir DIR] [-custom-data-dir DIR] [-dry-run] <dir-or-archive>")
+		return 2
+	}
+	switch args[0] {
+	case "import":
+		return runIDsImport(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown ids subcommand: %s\n", args[0])
+		return 2
+	}
+}
+
+func runIDsImport(args []string) int {
+	fs := flag.NewFlagSet("ids import", flag.ContinueOnError)
+	dataDir := fs.String("data-dir", "./data", "Data directory root (expects default/ and custom/ subfolders)")
+	customDir := fs.String("custom-data-dir", "", "Custom data directory to install into (overrides <data-dir>/custom)")
+	dryRun := fs.Bool("dry-run", false, "Validate and report changes without installing")
+	examples := fs.Int("examples", 5, "Number of example keys to print per change type")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: pible ids import [-data-dir DIR] [-custom-data-dir DIR] [-dry-run] <dir-or-archive>")
+		return 2
+	}
+
+	res, err := ids.Import(ids.ImportConfig{
+		LoadConfig: ids.LoadConfig{DataDir: strings.TrimSpace(*dataDir), CustomDir: strings.TrimSpace(*customDir)},
+		Source:     fs.Arg(0),
+		DryRun:     *dryRun,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] import failed: %v\n", err)
+		return 1
+	}
+
+	for _, d := range res.Files {
+		fmt.Printf("%s: %d entries, +%d added, -%d removed, ~%d changed\n", d.Name, d.Entries, len(d.Added), len(d.Removed), len(d.Changed))
+		printExamples("+", d.Added, *examples)
+		printExamples("-", d.Removed, *examples)
+		printExamples("~", d.Changed, *examples)
+	}
+	if *dryRun {
+		fmt.Println("dry run: nothing installed")
+		return 0
+	}
+	fmt.Printf("installed %d file(s) from %s at %s\n", len(res.Files), res.Manifest.Source, res.Manifest.InstalledAt)
+	fmt.Println("note: removed entries stay resolvable while they are present in data/default")
+	return 0
+}
+
+func printExamples(prefix string, keys []string, n int) {
+	for i, k := range keys {
+		if i >= n {
+			fmt.Printf("    %s ... %d more\n", prefix, len(keys)-n)
+			return
+		}
+		fmt.Printf("    %s %s\n", prefix, k)
+	}
+}
@@ -21,6 +21,23 @@ import (
 )
 
 func main() {
+	// Subcommands (non-scanning tools).
+	if len(os.Args) > 1 && os.Args[1] == "ids" {
+		os.Exit(runIDsCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "patterns" {
+		os.Exit(runPatternsCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "gatt" {
+		os.Exit(runGattCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "connect" {
+		os.Exit(runConnectCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rssi" {
+		os.Exit(runRSSICommand(os.Args[2:]))
+	}
+
 	var (
 		useGPSFlag      = flag.String("use-gps", "", "Use GPS? 'y' to enable, 'n' to skip.")
 		gpsModeFlag     = flag.String("gps-mode", "auto", "GPS mode: auto|gpsd|serial|off")
@@ -34,8 +51,29 @@ func main() {
 		restartBlueZSvc = flag.Bool("restart-bluetooth", true, "Preflight: restart bluetooth service if adapters are missing (requires root + systemctl)")
 		bluezCacheMode  = flag.String("bluez-cache", "auto", "Preflight: BlueZ device cache cleanup mode: auto|off|force")
 		statsInterval   = flag.Int("stats-interval", 5, "Console status interval in seconds")
+		notifyWindow    = flag.Duration("notify-window", 0, "Collect notify/indicate values for this long per connection (e.g. 10s; 0 disables, max 30s)")
+		gattMaxAge      = flag.Duration("gatt-max-age", 0, "Re-read GATT values of known devices once their last dump is older than this, e.g. 24h (0 = connect once, the default)")
+		gattRefreshType = flag.String("gatt-refresh-types", "", "Comma-separated marked device types to re-read once per session (e.g., cokeon)")
+		gattMaxReads    = flag.Int("gatt-max-reads", 40, "Maximum reads per GATT dump, counting characteristics, descriptors and long-value continuations (0 = only limited by -gatt-read-budget)")
+		gattReadTimeout = flag.Duration("gatt-read-timeout", 900*time.Millisecond, "Initial timeout per GATT read; raised up to 5s for slow devices")
+		gattReadBudget  = flag.Duration("gatt-read-budget", 20*time.Second, "Total time spent reading values per GATT dump (0 = unlimited)")
+		scanModeFlag    = flag.String("scan-mode", "active", "Scan mode: active|passive, or per adapter (e.g., active,hci1=passive). Passive uses BlueZ advertisement monitors (LE only, no scan requests).")
+		transportFlag   = flag.String("transport", "auto", "Discovery transport for active scanning: auto|le|bredr, or per adapter (e.g., le,hci0=auto)")
+		adapterRoles    = flag.String("adapter-roles", "both", "Adapter roles: both|scan|connect|classic, per adapter by hci ID or controller address (e.g., hci0=scan,00:1A:7D:DA:71:13=connect). connect needs bluetoothd -E (experimental ConnectDevice); without it the adapter falls back to both")
+		passivePatterns = flag.String("passive-patterns", "", "Advertisement patterns for passive scanning as adtype:hexcontent[@offset], comma-separated (e.g., ff:4c00,16:aafe). Empty uses built-in patterns.")
 
 		connectBlacklistFlag = flag.String("connect-blacklist", "", "Path to connection blacklist file (keywords; case-insensitive substring match). If empty, uses <custom data dir>/connect_blacklist.txt when present.")
+		connectWatchlistFlag = flag.String("connect-watchlist", "", "Path to connection watchlist file (MAC addresses or name keywords, connected first). If empty, uses <custom data dir>/connect_watchlist.txt when present.")
+		maxPerAdapterFlag    = flag.Int("max-connect-per-adapter", 0, "Limit on simultaneous connections per adapter (0 = same as the total limit)")
+		pairFlag             = flag.Bool("pair", false, "Pair (Just Works) with allow-listed devices whose characteristics need authentication")
+		pairAllowFlag        = flag.String("pair-allowlist", "", "Path to the pairing allow-list (MAC addresses or name keywords). If empty, uses <custom data dir>/pair_allowlist.txt.")
+		pairKeepBondFlag     = flag.Bool("pair-keep-bond", false, "Keep bonds created by -pair instead of removing them after the dump")
+		rssiWatchlistFlag    = flag.String("rssi-watchlist", "", "Path to the RSSI capture watchlist (MAC addresses or name keywords). If empty, uses <custom data dir>/rssi_watchlist.txt when present.")
+		rssiTypesFlag        = flag.String("rssi-capture-types", "", "Comma-separated marked device types whose every RSSI update is stored (rssi_samples)")
+		rssiMACsFlag         = flag.String("rssi-capture", "", "Comma-separated MAC addresses whose every RSSI update is stored (rssi_samples)")
+		rssiAlphaFlag        = flag.Float64("rssi-ema-alpha", 0.3, "EMA smoothing factor of captured RSSI (0..1, higher follows changes faster)")
+		rssiPathLossFlag     = flag.Float64("rssi-path-loss", 2.0, "Path loss exponent for captured distance estimates (2 = free space, 2.5-4 indoors)")
+		maxBackoffFlag       = flag.Duration("connect-max-backoff", 24*time.Hour, "Upper bound of the retry delay for devices that keep failing to connect")
 	)
 	flag.Parse()
 
@@ -64,21 +102,30 @@ func main() {
 		os.Exit(1)
 	}
 
+	if v := resolver.Version(); v != "" {
+		util.Linef("[IDS]", util.ColorGray, "identifier data: %s", v)
+	}
+
 	// Load device type detection patterns (optional).
 	patterns, perr := bluetooth.LoadDeviceTypePatterns(strings.TrimSpace(*dataDirFlag), strings.TrimSpace(*customDataFlag))
 	if perr != nil {
-		// Non-fatal: scanning still works without type detection.
-		patterns = nil
-		util.Linef("[WARN]", util.ColorYellow, "failed to load device type patterns: %v", perr)
+		// Non-fatal: scanning goes on with the patterns that did load, and
+		// the file is picked up again once it is fixed.
+		util.Linef("[WARN]", util.ColorYellow, "device type patterns: %v", perr)
+		log.Printf("device type patterns load failed: %v", perr)
+	}
+
+	// SIGHUP re-reads identifier files and device type patterns.
+	go reloadOnSIGHUP(ctx, resolver, patterns)
+
+	customDir := strings.TrimSpace(*customDataFlag)
+	if customDir == "" {
+		customDir = filepath.Join(strings.TrimSpace(*dataDirFlag), "custom")
 	}
 
 	// Connection blacklist (optional).
 	blacklistPath := strings.TrimSpace(*connectBlacklistFlag)
 	if blacklistPath == "" {
-		customDir := strings.TrimSpace(*customDataFlag)
-		if customDir == "" {
-			customDir = filepath.Join(strings.TrimSpace(*dataDirFlag), "custom")
-		}
 		blacklistPath = filepath.Join(customDir, "connect_blacklist.txt")
 	}
 	blacklist, blErr := bluetooth.LoadConnectBlacklist(blacklistPath)
@@ -89,6 +136,47 @@ func main() {
 		util.Linef("[FILTER]", util.ColorGray, "connect blacklist: %d keywords (%s)", len(blacklist.Keywords()), blacklist.Path())
 	}
 
+	// Connection watchlist (optional).
+	watchlistPath := strings.TrimSpace(*connectWatchlistFlag)
+	if watchlistPath == "" {
+		watchlistPath = filepath.Join(customDir, "connect_watchlist.txt")
+	}
+	watchlist, wlErr := bluetooth.LoadDeviceList(watchlistPath)
+	if wlErr != nil {
+		util.Linef("[WARN]", util.ColorYellow, "failed to load connect watchlist: %v", wlErr)
+		watchlist = nil
+	} else if watchlist != nil {
+		util.Linef("[FILTER]", util.ColorGray, "connect watchlist: %d entries (%s)", len(watchlist.Entries()), watchlist.Path())
+	}
+
+	// Scan mode/transport per adapter.
+	scanPlan, err := bluetooth.ParseScanPlan(*scanModeFlag, *transportFlag)
+	if err != nil {
+		util.Linef("[ERROR]", util.ColorYellow, "%v", err)
+		os.Exit(2)
+	}
+	if scanPlan.Patterns, err = bluetooth.ParseMonitorPatterns(*passivePatterns); err != nil {
+		util.Linef("[ERROR]", util.ColorYellow, "-passive-patterns: %v", err)
+		os.Exit(2)
+	}
+	if scanPlan.Roles, err = bluetooth.ParseAdapterRoles(*adapterRoles); err != nil {
+		util.Linef("[ERROR]", util.ColorYellow, "-adapter-roles: %v", err)
+		os.Exit(2)
+	}
+
+	// High-resolution RSSI capture watchlist (optional).
+	rssiWatchlistPath := strings.TrimSpace(*rssiWatchlistFlag)
+	if rssiWatchlistPath == "" {
+		rssiWatchlistPath = filepath.Join(customDir, "rssi_watchlist.txt")
+	}
+	rssiWatchlist, rwErr := bluetooth.LoadDeviceList(rssiWatchlistPath)
+	if rwErr != nil {
+		util.Linef("[WARN]", util.ColorYellow, "failed to load RSSI watchlist: %v", rwErr)
+		rssiWatchlist = nil
+	} else if rssiWatchlist != nil {
+		util.Linef("[FILTER]", util.ColorGray, "RSSI watchlist: %d entries (%s)", len(rssiWatchlist.Entries()), rssiWatchlist.Path())
+	}
+
 	// GPS selection.
 	useGPS := false
 	mode := strings.ToLower(strings.TrimSpace(*gpsModeFlag))
@@ -192,6 +280,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	for _, a := range chosenAdapters {
+		util.Linef("[SCAN]", util.ColorGray, "adapter=%s mode=%s", a, scanPlan.For(a))
+	}
+
 	adaptersJoined := strings.Join(chosenAdapters, ",")
 	adaptersJoinedDisplay := joinAdaptersDisplay(chosenAdapters, displayByID)
 
@@ -236,10 +328,78 @@ func main() {
 	}
 	util.Linef("[SESSION]", util.ColorGray, "id=%d adapters=%s", sessionID, adaptersJoinedDisplay)
 
-	// Periodic status (GPS/DB/Battery).
-	go status.Run(ctx, time.Duration(*statsInterval)*time.Second, status.Provider{GPS: gpsState, Store: store})
+	// High-resolution RSSI capture; nil when nothing is selected.
+	var capture *bluetooth.RSSICapture
+	rssiTypes := bluetooth.SplitMarkedTypes(*rssiTypesFlag)
+	rssiMACs := strings.TrimSpace(*rssiMACsFlag)
+	if rssiWatchlist != nil || len(rssiTypes) > 0 || rssiMACs != "" {
+		capture = bluetooth.NewRSSICapture(bluetooth.RSSICaptureConfig{
+			Watchlist:   rssiWatchlist,
+			MarkedTypes: rssiTypes,
+			Alpha:       *rssiAlphaFlag,
+			PathLoss:    *rssiPathLossFlag,
+		}, store, sessionID)
+		for _, mac := range strings.Split(rssiMACs, ",") {
+			capture.Select(mac)
+		}
+	}
+
+	sched := bluetooth.NewConnectScheduler(bluetooth.ConnectSchedulerConfig{
+		MaxTotal:      maxConn,
+		MaxPerAdapter: *maxPerAdapterFlag,
+		StaleAfter:    30 * time.Second,
+		Cooldown:      30 * time.Minute,
+		MaxBackoff:    *maxBackoffFlag,
+		Watchlist:     watchlist,
+	})
+	if n, err := sched.LoadBackoff(ctx, store); err != nil {
+		util.Linef("[WARN]", util.ColorYellow, "failed to load connection backoff: %v", err)
+	} else if n > 0 {
+		util.Linef("[BACKOFF]", util.ColorGray, "%d device(s) still backing off after failed connections", n)
+	}
+
+	// Periodic status (GPS/DB/Queue/Battery).
+	go status.Run(ctx, time.Duration(*statsInterval)*time.Second, status.Provider{GPS: gpsState, Store: store, Queue: sched})
+
+	connectOpts := bluetooth.ConnectOptions{
+		NotifyWindow: *notifyWindow,
+		Refresh: bluetooth.GattRefreshPolicy{
+			MaxAge:       *gattMaxAge,
+			SessionTypes: bluetooth.SplitMarkedTypes(*gattRefreshType),
+		},
+		Reads: bluetooth.DefaultGattReadOptions(),
+	}
+	connectOpts.Reads.MaxReads = *gattMaxReads
+	connectOpts.Reads.ReadTimeout = *gattReadTimeout
+	connectOpts.Reads.Budget = *gattReadBudget
+	if connectOpts.NotifyWindow > 30*time.Second {
+		connectOpts.NotifyWindow = 30 * time.Second
+	}
+	// Opt-in pairing agent (lab devices only).
+	if *pairFlag {
+		allowPath := strings.TrimSpace(*pairAllowFlag)
+		if allowPath == "" {
+			allowPath = filepath.Join(customDir, "pair_allowlist.txt")
+		}
+		allow, aErr := bluetooth.LoadDeviceList(allowPath)
+		switch {
+		case aErr != nil:
+			util.Linef("[WARN]", util.ColorYellow, "pairing disabled: failed to load allow-list: %v", aErr)
+		case allow == nil || len(allow.Entries()) == 0:
+			util.Linef("[WARN]", util.ColorYellow, "pairing disabled: allow-list %s is missing or empty", allowPath)
+		default:
+			policy, pErr := bluetooth.RegisterPairingAgent(ctx, allow, !*pairKeepBondFlag)
+			if pErr != nil {
+				util.Linef("[WARN]", util.ColorYellow, "pairing disabled: agent registration failed: %v", pErr)
+			} else {
+				connectOpts.Pairing = policy
+				defer policy.Close()
+				util.Linef("[PAIR]", util.ColorGray, "Just Works agent registered; allow-list: %d entries (%s)", len(allow.Entries()), allow.Path())
+			}
+		}
+	}
 
-	if err := bluetooth.StartContinuousScanAndConnectMulti(ctx, chosenAdapters, store, gpsState, resolver, patterns, sessionID, maxConn, tagPtr, blacklist); err != nil {
+	if err := bluetooth.StartContinuousScanAndConnectMulti(ctx, chosenAdapters, store, gpsState, resolver, patterns, sessionID, sched, capture, tagPtr, blacklist, scanPlan, connectOpts); err != nil {
 		if ctx.Err() != nil {
 			util.Line("[EXIT]", util.ColorGray, "stopping")
 			return
@@ -372,6 +532,37 @@ func signalContext(parent context.Context) (context.Context, context.CancelFunc)
 	return ctx, cancel
 }
 
+// reloadOnSIGHUP swaps in freshly loaded identifier data and device type
+// patterns on every SIGHUP. A failed reload keeps the data in use.
+func reloadOnSIGHUP(ctx context.Context, resolver *ids.Resolver, patterns *bluetooth.DeviceTypePatterns) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+		}
+		if resolver != nil {
+			if err := resolver.Reload(); err != nil {
+				util.Linef("[WARN]", util.ColorYellow, "identifier data not reloaded, keeping previous: %v", err)
+				log.Printf("ids reload failed: %v", err)
+			} else {
+				util.Linef("[RELOAD]", util.ColorGray, "identifier data: %s", resolver.Version())
+			}
+		}
+		if patterns != nil {
+			if err := patterns.Reload(); err != nil {
+				util.Linef("[WARN]", util.ColorYellow, "device type patterns not reloaded, keeping previous: %v", err)
+				log.Printf("device type patterns reload failed: %v", err)
+			} else {
+				util.Linef("[RELOAD]", util.ColorGray, "device type patterns reloaded")
+			}
+		}
+	}
+}
+
 func printLogo() {
 	logo := `
     _/_/_/    _/  _/_/_/    _/        _/_/_/_/
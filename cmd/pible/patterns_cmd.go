@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"pible/internal/bluetooth"
+)
+
+const patternsTestUsage = "usage: pible patterns test [-db FILE] [-data-dir DIR] [-custom-data-dir DIR] [-pattern NAME] [-session N] [-write] [-all]"
+
+// runPatternsCommand handles "pible patterns <subcommand> ...".
+func runPatternsCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, patternsTestUsage)
+		return 2
+	}
+	switch args[0] {
+	case "test":
+		return runPatternsTest(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown patterns subcommand: %s\n", args[0])
+		return 2
+	}
+}
+
+// runPatternsTest replays device_types.yaml over the stored advertisements
+// and reports which devices would be tagged.
+func runPatternsTest(args []string) int {
+	fs := flag.NewFlagSet("patterns test", flag.ContinueOnError)
+	dbPath := fs.String("db", "bluetooth_devices.db", "SQLite database to evaluate")
+	dataDir := fs.String("data-dir", "./data", "Data directory root (expects default/ and custom/ subfolders)")
+	customDir := fs.String("custom-data-dir", "", "Optional custom data directory path (overrides <data-dir>/custom)")
+	pattern := fs.String("pattern", "", "Only evaluate this pattern name")
+	session := fs.Int64("session", 0, "Only evaluate observations of this session ID (0 = all)")
+	write := fs.Bool("write", false, "Write the new types back to devices.type")
+	all := fs.Bool("all", false, "Also list matching devices whose stored type is unchanged")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, patternsTestUsage)
+		return 2
+	}
+
+	patterns, err := bluetooth.LoadDeviceTypePatterns(strings.TrimSpace(*dataDir), strings.TrimSpace(*customDir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] device type patterns: %v\n", err)
+		return 1
+	}
+	only := strings.ToLower(strings.TrimSpace(*pattern))
+	if only != "" {
+		found := false
+		for _, t := range patterns.Types {
+			if t.Name == only {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "[ERROR] unknown pattern: %s\n", only)
+			return 1
+		}
+	}
+
+	store, code := openExistingStore(*dbPath)
+	if store == nil {
+		return code
+	}
+	defer store.Close()
+
+	var sessionID *int64
+	if *session > 0 {
+		sessionID = session
+	}
+	ctx := context.Background()
+	results, err := bluetooth.ReplayPatterns(ctx, store, patterns, sessionID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] replay failed: %v\n", err)
+		return 1
+	}
+
+	var matched, added, removed, written int
+	for _, r := range results {
+		next := r.Matched
+		if only != "" {
+			// Only this pattern changes; other stored types are kept.
+			next = nil
+			for _, t := range r.Stored {
+				if t != only {
+					next = append(next, t)
+				}
+			}
+			if containsString(r.Matched, only) {
+				next = append(next, only)
+			}
+		}
+		plus := missingFrom(next, r.Stored)
+		minus := missingFrom(r.Stored, next)
+		if len(next) > 0 && (only == "" || containsString(next, only)) {
+			matched++
+		}
+		added += len(plus)
+		removed += len(minus)
+
+		changed := len(plus) > 0 || len(minus) > 0
+		if changed || (*all && len(r.Matched) > 0) {
+			fmt.Printf("%s %-17s %-24q stored=[%s] new=[%s]%s\n", changeMark(plus, minus), r.MAC, r.Name,
+				strings.Join(r.Stored, ","), strings.Join(next, ","), changeDetail(plus, minus))
+		}
+		if changed && *write {
+			if err := store.SetDeviceMarkedType(ctx, r.MAC, strings.Join(next, ",")); err != nil {
+				fmt.Fprintf(os.Stderr, "[ERROR] write %s: %v\n", r.MAC, err)
+				return 1
+			}
+			written++
+		}
+	}
+
+	fmt.Printf("%d devices evaluated, %d matched, +%d type(s) added, -%d removed\n", len(results), matched, added, removed)
+	if *write {
+		fmt.Printf("updated devices.type for %d device(s)\n", written)
+	} else if added+removed > 0 {
+		fmt.Println("dry run: use -write to store the new types")
+	}
+	return 0
+}
+
+func changeMark(plus, minus []string) string {
+	switch {
+	case len(plus) > 0 && len(minus) > 0:
+		return "~"
+	case len(plus) > 0:
+		return "+"
+	case len(minus) > 0:
+		return "-"
+	}
+	return "="
+}
+
+func changeDetail(plus, minus []string) string {
+	var parts []string
+	for _, p := range plus {
+		parts = append(parts, "+"+p)
+	}
+	for _, m := range minus {
+		parts = append(parts, "-"+m)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, " ") + ")"
+}
+
+// missingFrom returns the items of a that are not in b.
+func missingFrom(a, b []string) []string {
+	var out []string
+	for _, v := range a {
+		if !containsString(b, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	rssiAdaptersUsage = "usage: pible rssi adapters [-db FILE] [-session ID] <mac>"
+	rssiSamplesUsage  = "usage: pible rssi samples [-db FILE] [-session ID] [-limit N] <mac>"
+)
+
+// runRSSICommand handles "pible rssi <subcommand> ...".
+func runRSSICommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, rssiAdaptersUsage)
+		fmt.Fprintln(os.Stderr, rssiSamplesUsage)
+		return 2
+	}
+	switch args[0] {
+	case "adapters":
+		return runRSSIAdapters(args[1:])
+	case "samples":
+		return runRSSISamples(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown rssi subcommand: %s\n", args[0])
+		return 2
+	}
+}
+
+// runRSSIAdapters compares how strongly each adapter received one device.
+func runRSSIAdapters(args []string) int {
+	fs := flag.NewFlagSet("rssi adapters", flag.ContinueOnError)
+	dbPath := fs.String("db", "bluetooth_devices.db", "SQLite database to read")
+	session := fs.Int64("session", 0, "Only use this session (0 = all sessions)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, rssiAdaptersUsage)
+		return 2
+	}
+	mac := strings.ToUpper(strings.TrimSpace(fs.Arg(0)))
+
+	store, code := openExistingStore(*dbPath)
+	if store == nil {
+		return code
+	}
+	defer store.Close()
+
+	obs, err := store.ListAdapterObservations(context.Background(), mac, *session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] read adapter observations: %v\n", err)
+		return 1
+	}
+	if len(obs) == 0 {
+		fmt.Printf("no per-adapter RSSI stored for %s\n", mac)
+		return 0
+	}
+	best := obs[0].RSSIAvg
+	for _, o := range obs {
+		fmt.Printf("%-17s %-32q avg %6.1f dBm (%+5.1f) min %4d max %4d n=%-6d sessions=%d %s .. %s\n",
+			o.AdapterAddr, o.Adapter, o.RSSIAvg, o.RSSIAvg-best, o.RSSIMin, o.RSSIMax, o.Count, o.Sessions, o.FirstSeen, o.LastSeen)
+	}
+	return 0
+}
+
+// runRSSISamples prints the captured RSSI time series of one device as
+// tab-separated values.
+func runRSSISamples(args []string) int {
+	fs := flag.NewFlagSet("rssi samples", flag.ContinueOnError)
+	dbPath := fs.String("db", "bluetooth_devices.db", "SQLite database to read")
+	session := fs.Int64("session", 0, "Only use this session (0 = all sessions)")
+	limit := fs.Int("limit", 1000, "Print at most the newest N samples (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, rssiSamplesUsage)
+		return 2
+	}
+	mac := strings.ToUpper(strings.TrimSpace(fs.Arg(0)))
+
+	store, code := openExistingStore(*dbPath)
+	if store == nil {
+		return code
+	}
+	defer store.Close()
+
+	samples, err := store.ListRSSISamples(context.Background(), mac, *session, *limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] read RSSI samples: %v\n", err)
+		return 1
+	}
+	if len(samples) == 0 {
+		fmt.Printf("no RSSI samples stored for %s\n", mac)
+		return 0
+	}
+	fmt.Println("timestamp\tsession\tadapter\trssi\tsmoothed\ttx_power\tdistance_m")
+	for _, r := range samples {
+		txp, dist := "-", "-"
+		if r.TxPower != nil {
+			txp = fmt.Sprintf("%d", *r.TxPower)
+		}
+		if r.DistanceM != nil {
+			dist = fmt.Sprintf("%.2f", *r.DistanceM)
+		}
+		fmt.Printf("%s\t%d\t%s\t%d\t%.1f\t%s\t%s\n", r.Timestamp, r.SessionID, r.AdapterAddr, r.RSSI, r.RSSISmoothed, txp, dist)
+	}
+	return 0
+}
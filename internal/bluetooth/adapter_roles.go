@@ -0,0 +1,76 @@
+package bluetooth
+
+import (
+	"fmt"
+	"strings"
+
+	"pible/internal/util"
+)
+
+// Adapter roles.
+const (
+	RoleBoth    = "both"    // discovery and connections (default)
+	RoleScan    = "scan"    // discovery only; never connects
+	RoleConnect = "connect" // connections only, for devices other adapters see; no discovery
+	RoleClassic = "classic" // BR/EDR inquiry only; never connects
+)
+
+// AdapterRoles assigns roles to adapters by hci ID or controller address.
+// Address entries win, so a role follows a dongle across hci renumbering.
+type AdapterRoles struct {
+	Default string
+	byID    map[string]string
+	byAddr  map[string]string
+}
+
+// ParseAdapterRoles parses a comma-separated list of adapter=role entries,
+// where adapter is an hci ID or a controller address, optionally with a plain
+// role as the default, e.g. "hci0=scan,00:1A:7D:DA:71:13=connect".
+func ParseAdapterRoles(spec string) (AdapterRoles, error) {
+	r := AdapterRoles{Default: RoleBoth, byID: map[string]string{}, byAddr: map[string]string{}}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		// Addresses contain ':' themselves, so split on the last '='.
+		adapter, role := "", part
+		if i := strings.LastIndex(part, "="); i >= 0 {
+			adapter, role = strings.TrimSpace(part[:i]), part[i+1:]
+		}
+		role = strings.ToLower(strings.TrimSpace(role))
+		switch role {
+		case RoleBoth, RoleScan, RoleConnect, RoleClassic:
+		default:
+			return AdapterRoles{}, fmt.Errorf("invalid adapter role %q (want both, scan, connect, classic)", role)
+		}
+		switch {
+		case adapter == "":
+			r.Default = role
+		case util.IsMACAddress(adapter):
+			r.byAddr[normalizeAdapterAddr(adapter)] = role
+		default:
+			r.byID[strings.ToLower(adapter)] = role
+		}
+	}
+	return r, nil
+}
+
+// For returns the role of an adapter given its hci ID and controller address
+// (which may be empty when unknown).
+func (r AdapterRoles) For(adapterID, addr string) string {
+	if role, ok := r.byAddr[normalizeAdapterAddr(addr)]; ok {
+		return role
+	}
+	if role, ok := r.byID[strings.ToLower(strings.TrimSpace(adapterID))]; ok {
+		return role
+	}
+	if r.Default == "" {
+		return RoleBoth
+	}
+	return r.Default
+}
+
+func normalizeAdapterAddr(addr string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(addr), "-", ":"))
+}
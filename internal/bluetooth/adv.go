@@ -24,6 +24,12 @@ func buildAdvertisementJSON(
 		"manufacturer":  mfg,
 		"service_data":  svcData,
 	}
+	if decoded := decodeAdvertisement(mfg, svcData); !decoded.Empty() {
+		payload["decoded"] = decoded.Frames
+		if decoded.Product != "" {
+			payload["product"] = decoded.Product
+		}
+	}
 
 	if advBytes != nil && len(advBytes) > 0 {
 		advCopy := append([]byte(nil), advBytes...)
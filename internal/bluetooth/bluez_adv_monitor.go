@@ -0,0 +1,179 @@
+package bluetooth
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// MonitorPattern is one or_patterns entry of an advertisement monitor: the
+// advertisement matches when the AD structure of type ADType holds Content
+// at offset Start.
+type MonitorPattern struct {
+	Start   uint8
+	ADType  uint8
+	Content []byte
+}
+
+func (p MonitorPattern) String() string {
+	s := fmt.Sprintf("%02x:%s", p.ADType, hex.EncodeToString(p.Content))
+	if p.Start > 0 {
+		s += "@" + strconv.Itoa(int(p.Start))
+	}
+	return s
+}
+
+// DefaultMonitorPatterns matches most advertisers: the common Flags values
+// plus manufacturer and service data of the big non-connectable beacon
+// families (which often omit Flags).
+func DefaultMonitorPatterns() []MonitorPattern {
+	pats := make([]MonitorPattern, 0, 24)
+	for _, f := range []byte{0x02, 0x04, 0x05, 0x06, 0x0a, 0x12, 0x18, 0x19, 0x1a, 0x1b, 0x1e, 0x1f} {
+		pats = append(pats, MonitorPattern{ADType: 0x01, Content: []byte{f}})
+	}
+	for _, cid := range []uint16{0x004c, 0x0006, 0x0075, 0x00e0, 0x0087} { // Apple, Microsoft, Samsung, Google, Garmin
+		pats = append(pats, MonitorPattern{ADType: 0xff, Content: []byte{byte(cid), byte(cid >> 8)}})
+	}
+	for _, uuid := range []uint16{0xfeaa, 0xfe2c, 0xfd6f, 0xfe9f} { // Eddystone, Fast Pair, Exposure Notification, Google
+		pats = append(pats, MonitorPattern{ADType: 0x16, Content: []byte{byte(uuid), byte(uuid >> 8)}})
+	}
+	return pats
+}
+
+// ParseMonitorPatterns parses comma-separated "adtype:content[@start]"
+// patterns with hex AD type and content, e.g. "ff:4c00,16:aafe,09:50@0".
+func ParseMonitorPatterns(spec string) ([]MonitorPattern, error) {
+	var out []MonitorPattern
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		start := 0
+		if body, off, ok := strings.Cut(part, "@"); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(off))
+			if err != nil || n < 0 || n > 30 {
+				return nil, fmt.Errorf("pattern %q: invalid start offset", part)
+			}
+			part, start = body, n
+		}
+		typ, content, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("pattern %q: want adtype:content", part)
+		}
+		t, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(typ)), "0x"), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: invalid AD type", part)
+		}
+		b, err := hex.DecodeString(strings.TrimSpace(content))
+		if err != nil || len(b) == 0 || start+len(b) > 31 {
+			return nil, fmt.Errorf("pattern %q: invalid content", part)
+		}
+		out = append(out, MonitorPattern{Start: uint8(start), ADType: uint8(t), Content: b})
+	}
+	return out, nil
+}
+
+// advMonitor is an org.bluez.AdvertisementMonitor1. BlueZ creates/updates the
+// Device1 objects of matching devices, which the discovery loop snapshots as
+// usual; the callbacks are only counted.
+type advMonitor struct {
+	found atomic.Int64
+}
+
+func (m *advMonitor) Release() *dbus.Error { return nil }
+
+func (m *advMonitor) Activate() *dbus.Error { return nil }
+
+func (m *advMonitor) DeviceFound(dev dbus.ObjectPath) *dbus.Error {
+	m.found.Add(1)
+	return nil
+}
+
+func (m *advMonitor) DeviceLost(dev dbus.ObjectPath) *dbus.Error { return nil }
+
+// monitorApp is the object tree registered with AdvertisementMonitorManager1:
+// an ObjectManager root with a single or_patterns monitor below it.
+type monitorApp struct {
+	root    dbus.ObjectPath
+	monitor dbus.ObjectPath
+	props   map[string]dbus.Variant
+}
+
+func (a *monitorApp) GetManagedObjects() (map[dbus.ObjectPath]map[string]map[string]dbus.Variant, *dbus.Error) {
+	return map[dbus.ObjectPath]map[string]map[string]dbus.Variant{
+		a.monitor: {"org.bluez.AdvertisementMonitor1": a.props},
+	}, nil
+}
+
+// passiveScan is a registered advertisement monitor on one adapter.
+type passiveScan struct {
+	conn    *dbus.Conn
+	adapter dbus.ObjectPath
+	app     *monitorApp
+	mon     *advMonitor
+}
+
+// startPassiveScan registers an or_patterns advertisement monitor on the
+// adapter. BlueZ then scans passively (no scan requests) for as long as the
+// monitor is registered.
+func startPassiveScan(ctx context.Context, conn *dbus.Conn, adapterID string, patterns []MonitorPattern) (*passiveScan, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultMonitorPatterns()
+	}
+	root := dbus.ObjectPath("/pible/monitor/" + adapterID)
+	app := &monitorApp{
+		root:    root,
+		monitor: root + "/m0",
+		props: map[string]dbus.Variant{
+			"Type":     dbus.MakeVariant("or_patterns"),
+			"Patterns": dbus.MakeVariant(patterns),
+		},
+	}
+	mon := &advMonitor{}
+	if err := conn.Export(app, root, "org.freedesktop.DBus.ObjectManager"); err != nil {
+		return nil, err
+	}
+	if err := conn.Export(mon, app.monitor, "org.bluez.AdvertisementMonitor1"); err != nil {
+		_ = conn.Export(nil, root, "org.freedesktop.DBus.ObjectManager")
+		return nil, err
+	}
+	propMap := prop.Map{"org.bluez.AdvertisementMonitor1": {}}
+	for k, v := range app.props {
+		propMap["org.bluez.AdvertisementMonitor1"][k] = &prop.Prop{Value: v.Value(), Emit: prop.EmitFalse}
+	}
+	if _, err := prop.Export(conn, app.monitor, propMap); err != nil {
+		unexportMonitorApp(conn, app)
+		return nil, err
+	}
+
+	p := &passiveScan{conn: conn, adapter: dbus.ObjectPath("/org/bluez/" + adapterID), app: app, mon: mon}
+	mgr := conn.Object("org.bluez", p.adapter)
+	if err := mgr.CallWithContext(ctx, "org.bluez.AdvertisementMonitorManager1.RegisterMonitor", 0, root).Err; err != nil {
+		unexportMonitorApp(conn, app)
+		return nil, err
+	}
+	return p, nil
+}
+
+// Found is the number of DeviceFound callbacks so far.
+func (p *passiveScan) Found() int64 { return p.mon.found.Load() }
+
+// Stop unregisters the monitor, which ends the passive scan.
+func (p *passiveScan) Stop() {
+	mgr := p.conn.Object("org.bluez", p.adapter)
+	_ = mgr.Call("org.bluez.AdvertisementMonitorManager1.UnregisterMonitor", 0, p.app.root).Err
+	unexportMonitorApp(p.conn, p.app)
+}
+
+func unexportMonitorApp(conn *dbus.Conn, app *monitorApp) {
+	_ = conn.Export(nil, app.monitor, "org.freedesktop.DBus.Properties")
+	_ = conn.Export(nil, app.monitor, "org.bluez.AdvertisementMonitor1")
+	_ = conn.Export(nil, app.root, "org.freedesktop.DBus.ObjectManager")
+}
@@ -0,0 +1,200 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"pible/internal/db"
+	"pible/internal/util"
+)
+
+const pairingAgentPath = dbus.ObjectPath("/pible/agent")
+
+// PairingPolicy enables opt-in pairing for devices on an allow-list.
+// Devices that return authentication errors on GATT reads are paired with
+// Just Works (NoInputNoOutput) and dumped again.
+type PairingPolicy struct {
+	Allow      *DeviceList
+	RemoveBond bool // remove the bond (and BlueZ device entry) after the dump
+	agent      *PairingAgent
+}
+
+// PairingAgent is an org.bluez.Agent1 with NoInputNoOutput capability.
+// It only accepts requests for devices pible is currently pairing with;
+// everything else is rejected.
+type PairingAgent struct {
+	conn *dbus.Conn
+
+	mu      sync.Mutex
+	pairing map[dbus.ObjectPath]bool
+}
+
+// RegisterPairingAgent exports the agent on the shared system bus connection
+// (the one the connect workers use) and registers it with BlueZ. It is not
+// requested as the default agent, so pairing requests started by other
+// clients or remote devices do not reach it.
+func RegisterPairingAgent(ctx context.Context, allow *DeviceList, removeBond bool) (*PairingPolicy, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+	a := &PairingAgent{conn: conn, pairing: map[dbus.ObjectPath]bool{}}
+	if err := conn.Export(a, pairingAgentPath, "org.bluez.Agent1"); err != nil {
+		return nil, err
+	}
+	mgr := conn.Object("org.bluez", dbus.ObjectPath("/org/bluez"))
+	if err := mgr.CallWithContext(ctx, "org.bluez.AgentManager1.RegisterAgent", 0, pairingAgentPath, "NoInputNoOutput").Err; err != nil {
+		_ = conn.Export(nil, pairingAgentPath, "org.bluez.Agent1")
+		return nil, err
+	}
+	return &PairingPolicy{Allow: allow, RemoveBond: removeBond, agent: a}, nil
+}
+
+// Close unregisters the agent.
+func (p *PairingPolicy) Close() {
+	if p == nil || p.agent == nil {
+		return
+	}
+	mgr := p.agent.conn.Object("org.bluez", dbus.ObjectPath("/org/bluez"))
+	_ = mgr.Call("org.bluez.AgentManager1.UnregisterAgent", 0, pairingAgentPath).Err
+	_ = p.agent.conn.Export(nil, pairingAgentPath, "org.bluez.Agent1")
+}
+
+func (a *PairingAgent) begin(dev dbus.ObjectPath) {
+	a.mu.Lock()
+	a.pairing[dev] = true
+	a.mu.Unlock()
+}
+
+func (a *PairingAgent) end(dev dbus.ObjectPath) {
+	a.mu.Lock()
+	delete(a.pairing, dev)
+	a.mu.Unlock()
+}
+
+func (a *PairingAgent) accept(dev dbus.ObjectPath) *dbus.Error {
+	a.mu.Lock()
+	ok := a.pairing[dev]
+	a.mu.Unlock()
+	if !ok {
+		return rejected()
+	}
+	return nil
+}
+
+func rejected() *dbus.Error { return dbus.NewError("org.bluez.Error.Rejected", nil) }
+
+// org.bluez.Agent1 methods. PIN and passkey entry are not supported.
+
+func (a *PairingAgent) Release() *dbus.Error { return nil }
+
+func (a *PairingAgent) RequestPinCode(dev dbus.ObjectPath) (string, *dbus.Error) {
+	return "", rejected()
+}
+
+func (a *PairingAgent) DisplayPinCode(dev dbus.ObjectPath, pincode string) *dbus.Error {
+	return a.accept(dev)
+}
+
+func (a *PairingAgent) RequestPasskey(dev dbus.ObjectPath) (uint32, *dbus.Error) {
+	return 0, rejected()
+}
+
+func (a *PairingAgent) DisplayPasskey(dev dbus.ObjectPath, passkey uint32, entered uint16) *dbus.Error {
+	return a.accept(dev)
+}
+
+func (a *PairingAgent) RequestConfirmation(dev dbus.ObjectPath, passkey uint32) *dbus.Error {
+	return a.accept(dev)
+}
+
+func (a *PairingAgent) RequestAuthorization(dev dbus.ObjectPath) *dbus.Error {
+	return a.accept(dev)
+}
+
+func (a *PairingAgent) AuthorizeService(dev dbus.ObjectPath, uuid string) *dbus.Error {
+	return a.accept(dev)
+}
+
+func (a *PairingAgent) Cancel() *dbus.Error { return nil }
+
+// countAuthErrors counts characteristics whose read failed for lack of
+// authentication or encryption.
+func countAuthErrors(attrs []GattAttribute) int {
+	n := 0
+	for _, a := range attrs {
+		if a.ReadError != "" && classifyConnectError(errors.New(a.ReadError)) == ConnErrAuthRequired {
+			n++
+		}
+	}
+	return n
+}
+
+// pairDevice pairs with a connected device through the agent.
+// It returns "paired" or "already_paired", or an error.
+func (p *PairingPolicy) pairDevice(ctx context.Context, conn *dbus.Conn, devPath dbus.ObjectPath) (string, error) {
+	if paired, ok := bluezDeviceBoolProp(conn, devPath, "Paired"); ok && paired {
+		return "already_paired", nil
+	}
+	p.agent.begin(devPath)
+	defer p.agent.end(devPath)
+	pairCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	err := conn.Object("org.bluez", devPath).CallWithContext(pairCtx, "org.bluez.Device1.Pair", 0).Err
+	if err != nil && strings.Contains(err.Error(), "AlreadyExists") {
+		return "already_paired", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return "paired", nil
+}
+
+// removeBond drops the device from BlueZ, which also deletes its keys.
+func removeBond(conn *dbus.Conn, adapterID string, devPath dbus.ObjectPath) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	adapter := conn.Object("org.bluez", dbus.ObjectPath("/org/bluez/"+adapterID))
+	return adapter.CallWithContext(ctx, "org.bluez.Adapter1.RemoveDevice", 0, devPath).Err
+}
+
+func bluezDeviceBoolProp(conn *dbus.Conn, devPath dbus.ObjectPath, name string) (bool, bool) {
+	v, err := conn.Object("org.bluez", devPath).GetProperty("org.bluez.Device1." + name)
+	if err != nil {
+		return false, false
+	}
+	b, ok := v.Value().(bool)
+	return b, ok
+}
+
+// recordPairingAttempt stores a pairing outcome and prints it.
+func recordPairingAttempt(store *db.Store, sessionID int64, mac, adapter, outcome string, err error, before int, after *int, bondRemoved bool) {
+	p := db.PairingAttemptParams{
+		SessionID:       &sessionID,
+		MAC:             mac,
+		Adapter:         adapter,
+		Timestamp:       util.NowTimestamp(),
+		Outcome:         outcome,
+		ProtectedBefore: before,
+		ProtectedAfter:  after,
+		BondRemoved:     bondRemoved,
+	}
+	if err != nil {
+		e := err.Error()
+		class := classifyConnectError(err)
+		p.Outcome = "failed"
+		p.Error = &e
+		p.ErrorClass = &class
+		util.Linef("[PAIR]", util.ColorYellow, "%s failed (%s): %v", mac, class, err)
+	} else if after != nil {
+		util.Linef("[PAIR]", util.ColorGreen, "%s %s; protected reads %d -> %d", mac, outcome, before, *after)
+	}
+	if store != nil {
+		_ = store.InsertPairingAttempt(context.Background(), p)
+	}
+}
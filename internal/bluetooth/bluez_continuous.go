@@ -14,6 +14,7 @@ import (
 	"github.com/godbus/dbus/v5"
 
 	"pible/internal/db"
+	"pible/internal/decode"
 	"pible/internal/gps"
 	"pible/internal/ids"
 	"pible/internal/util"
@@ -24,7 +25,10 @@ import (
 // Design goals:
 // - No short scan loops (no Start/Stop every few seconds). Discovery runs continuously.
 // - Avoid org.bluez.Error.InProgress by not starting concurrent discoveries on the same adapter.
-// - Scale to many devices: DB writes are throttled per MAC; connection attempts are rate-limited.
+// - Scale to many devices: DB writes are throttled per MAC; connections go through a shared ConnectScheduler.
+// - Each adapter scans actively or passively (AdvertisementMonitor1) as scan says.
+// - Adapters can be limited to scanning, connecting or BR/EDR inquiry (scan.Roles).
+// - Every RSSI update of devices selected by capture is stored (capture may be nil).
 func StartContinuousScanAndConnectMulti(
 	ctx context.Context,
 	adapterIDs []string,
@@ -33,46 +37,35 @@ func StartContinuousScanAndConnectMulti(
 	resolver *ids.Resolver,
 	patterns *DeviceTypePatterns,
 	sessionID int64,
-	maxConnectTotal int,
+	sched *ConnectScheduler,
+	capture *RSSICapture,
 	tag *string,
 	blacklist *ConnectBlacklist,
+	scan ScanPlan,
+	opts ConnectOptions,
 ) error {
 	if len(adapterIDs) == 0 {
 		return errors.New("no adapters")
 	}
-	if maxConnectTotal < 1 {
-		maxConnectTotal = 1
+	if sched == nil {
+		return errors.New("no connect scheduler")
 	}
 
 	gpsState.SetScanningStarted(true)
 
-	// Split connection concurrency across adapters.
-	per := maxConnectTotal / len(adapterIDs)
-	rest := maxConnectTotal % len(adapterIDs)
-	if per < 1 {
-		per = 1
-		rest = 0
-	}
-
-	// Build per-adapter connection limits.
-	limits := make(map[string]int, len(adapterIDs))
-	for i, a := range adapterIDs {
-		maxConn := per
-		if i < rest {
-			maxConn++
-		}
-		limits[a] = maxConn
-	}
-
 	// Run a managed worker per adapter with hot-plug support.
 	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		capture.Run(ctx)
+	}()
 	for _, a := range adapterIDs {
 		adapterID := a
-		maxConn := limits[adapterID]
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			runManagedAdapterLoop(ctx, adapterID, store, gpsState, resolver, patterns, sessionID, maxConn, tag, blacklist)
+			runManagedAdapterLoop(ctx, adapterID, store, gpsState, resolver, patterns, sessionID, sched, capture, tag, blacklist, scan, opts)
 		}()
 	}
 
@@ -88,14 +81,40 @@ func StartContinuousScanAndConnectMulti(
 	return ctx.Err()
 }
 
+// ConnectOptions controls what happens while a device is connected.
+type ConnectOptions struct {
+	// NotifyWindow is how long to collect notify/indicate values after the
+	// GATT dump. Zero disables the capture.
+	NotifyWindow time.Duration
+	// Refresh decides when devices with a stored GATT dump are read again.
+	Refresh GattRefreshPolicy
+	// Pairing, when set, pairs allow-listed devices whose reads need authentication.
+	Pairing *PairingPolicy
+	// Reads limits the characteristic reads of each GATT dump.
+	Reads GattReadOptions
+}
+
+// jobTimeout bounds one connect job: connect, service resolution, the GATT
+// dump(s) and the notification window.
+func (o ConnectOptions) jobTimeout() time.Duration {
+	d := 60*time.Second + o.NotifyWindow
+	// The default allows for a 20s read budget; give larger budgets the difference.
+	if extra := o.Reads.Budget - DefaultGattReadOptions().Budget; extra > 0 {
+		d += extra
+	}
+	return d
+}
+
 type bluezConfig struct {
 	SnapshotInterval      time.Duration
 	DeviceUpdateMinPeriod time.Duration
 	AdvInsertMinPeriod    time.Duration
+	SensorInsertMinPeriod time.Duration
+	SensorRepeatPeriod    time.Duration
 	ClassicHistMinPeriod  time.Duration
-	ConnectCooldown       time.Duration
+	RefreshCheckPeriod    time.Duration
+	ObservationFlush      time.Duration
 	ConnectRSSIMin        int
-	ConnectQueueSize      int
 	DiscoverFilterRSSI    int16
 	DuplicateData         bool
 }
@@ -105,10 +124,12 @@ func defaultBlueZConfig() bluezConfig {
 		SnapshotInterval:      3 * time.Second,
 		DeviceUpdateMinPeriod: 10 * time.Second,
 		AdvInsertMinPeriod:    30 * time.Second,
+		SensorInsertMinPeriod: 10 * time.Second,
+		SensorRepeatPeriod:    5 * time.Minute,
 		ClassicHistMinPeriod:  30 * time.Second,
-		ConnectCooldown:       30 * time.Minute,
+		RefreshCheckPeriod:    1 * time.Minute,
+		ObservationFlush:      30 * time.Second,
 		ConnectRSSIMin:        -75,
-		ConnectQueueSize:      8192,
 		DiscoverFilterRSSI:    int16(-90),
 		DuplicateData:         false,
 	}
@@ -122,11 +143,15 @@ func runBlueZDiscoveryLoop(
 	resolver *ids.Resolver,
 	patterns *DeviceTypePatterns,
 	sessionID int64,
-	maxConnect int,
+	sched *ConnectScheduler,
+	capture *RSSICapture,
 	tag *string,
 	blacklist *ConnectBlacklist,
+	scan ScanPlan,
+	opts ConnectOptions,
 ) error {
 	cfg := defaultBlueZConfig()
+	scanCfg := scan.For(adapterID)
 
 	adapterLabel := AdapterDisplayName(adapterID)
 
@@ -138,48 +163,122 @@ func runBlueZDiscoveryLoop(
 	adapterPath := dbus.ObjectPath("/org/bluez/" + adapterID)
 	adapterObj := conn.Object("org.bluez", adapterPath)
 
-	// Best-effort: set discovery filter. Ignore failures when another process is controlling discovery.
-	_ = adapterObj.CallWithContext(ctx, "org.bluez.Adapter1.SetDiscoveryFilter", 0, map[string]dbus.Variant{
-		"Transport":     dbus.MakeVariant("auto"),
-		"RSSI":          dbus.MakeVariant(cfg.DiscoverFilterRSSI),
-		"DuplicateData": dbus.MakeVariant(cfg.DuplicateData),
-	}).Err
+	// Role (looked up on every run: the hci ID may have changed after a hotplug).
+	adapterAddr := bluezAdapterAddress(ctx, conn, adapterID)
+	role := scan.Roles.For(adapterID, adapterAddr)
+	if role == RoleConnect {
+		// Connecting to devices the adapter has not discovered needs
+		// Adapter1.ConnectDevice, which BlueZ only offers in experimental mode.
+		ok, err := bluezAdapterHasMethod(ctx, conn, adapterID, "ConnectDevice")
+		switch {
+		case err != nil:
+			util.Linef("[WARN]", util.ColorYellow, "adapter=%s could not check for ConnectDevice: %v", adapterID, err)
+		case !ok:
+			util.Linef("[ERROR]", util.ColorYellow, "adapter=%s role=connect needs Adapter1.ConnectDevice (start bluetoothd with -E / --experimental); using role=both", adapterID)
+			role = RoleBoth
+		}
+	}
+	sched.SetConnectOnly(adapterID, role == RoleConnect)
+	if role != RoleBoth {
+		util.Linef("[ROLE]", util.ColorGray, "adapter=%s role=%s", adapterID, role)
+	}
+	switch role {
+	case RoleConnect:
+		// No discovery: only take connection jobs for devices other adapters see.
+		if store != nil {
+			_ = store.SetSessionScanMode(ctx, sessionID, adapterID, "connect-only")
+		}
+		for i := 0; i < sched.MaxPerAdapter(); i++ {
+			go bluezConnectWorker(ctx, conn, adapterID, adapterLabel, store, resolver, patterns, sessionID, tag, opts, sched)
+		}
+		<-ctx.Done()
+		sched.SetConnectOnly(adapterID, false)
+		return ctx.Err()
+	case RoleClassic:
+		if scanCfg.Mode == ScanPassive {
+			util.Linef("[WARN]", util.ColorYellow, "adapter=%s role=classic needs inquiry; ignoring passive scan mode", adapterID)
+		}
+		scanCfg = AdapterScanConfig{Mode: ScanActive, Transport: TransportBREDR}
+	}
 
-	// Start discovery once.
-	startedByUs := false
-	if err := adapterObj.CallWithContext(ctx, "org.bluez.Adapter1.StartDiscovery", 0).Err; err != nil {
-		// If already discovering, do not treat as fatal.
-		if !strings.Contains(err.Error(), "InProgress") {
-			log.Printf("bluez StartDiscovery %s error: %v", adapterID, err)
-		} else {
-			util.Linef("[SCAN]", util.ColorGray, "adapter=%s discovery already in progress (reusing)", adapterID)
+	if scanCfg.Mode == ScanPassive {
+		// Passive: no discovery session at all, only the advertisement monitor.
+		// Falling back to active scanning would defeat the purpose, so a
+		// registration failure ends this run (the adapter loop retries).
+		ps, err := startPassiveScan(ctx, conn, adapterID, scan.Patterns)
+		if err != nil {
+			util.Linef("[ERROR]", util.ColorYellow, "adapter=%s passive scan (advertisement monitor) failed: %v", adapterID, err)
+			return err
 		}
+		util.Linef("[SCAN]", util.ColorGray, "adapter=%s passive scan started (advertisement monitor)", adapterID)
+		defer func() {
+			ps.Stop()
+			util.Linef("[SCAN]", util.ColorGray, "adapter=%s passive scan stopped (%d device reports)", adapterID, ps.Found())
+		}()
 	} else {
-		startedByUs = true
-		util.Linef("[SCAN]", util.ColorGray, "adapter=%s discovery started", adapterID)
-	}
+		// Best-effort: set discovery filter. Ignore failures when another process is controlling discovery.
+		_ = adapterObj.CallWithContext(ctx, "org.bluez.Adapter1.SetDiscoveryFilter", 0, map[string]dbus.Variant{
+			"Transport":     dbus.MakeVariant(scanCfg.Transport),
+			"RSSI":          dbus.MakeVariant(cfg.DiscoverFilterRSSI),
+			"DuplicateData": dbus.MakeVariant(cfg.DuplicateData),
+		}).Err
+
+		// Start discovery once.
+		startedByUs := false
+		if err := adapterObj.CallWithContext(ctx, "org.bluez.Adapter1.StartDiscovery", 0).Err; err != nil {
+			// If already discovering, do not treat as fatal.
+			if !strings.Contains(err.Error(), "InProgress") {
+				log.Printf("bluez StartDiscovery %s error: %v", adapterID, err)
+			} else {
+				util.Linef("[SCAN]", util.ColorGray, "adapter=%s discovery already in progress (reusing)", adapterID)
+			}
+		} else {
+			startedByUs = true
+			util.Linef("[SCAN]", util.ColorGray, "adapter=%s discovery started (transport=%s)", adapterID, scanCfg.Transport)
+		}
 
-	// Stop discovery only if we started it.
-	defer func() {
-		if !startedByUs {
-			return
+		// Stop discovery only if we started it.
+		defer func() {
+			if !startedByUs {
+				return
+			}
+			_ = adapterObj.Call("org.bluez.Adapter1.StopDiscovery", 0).Err
+		}()
+	}
+	if store != nil {
+		mode := scanCfg.String()
+		if role != RoleBoth {
+			mode += " " + role + "-only"
 		}
-		_ = adapterObj.Call("org.bluez.Adapter1.StopDiscovery", 0).Err
-	}()
+		_ = store.SetSessionScanMode(ctx, sessionID, adapterID, mode)
+	}
 
-	if maxConnect < 1 {
-		maxConnect = 1
+	if role == RoleBoth {
+		for i := 0; i < sched.MaxPerAdapter(); i++ {
+			go bluezConnectWorker(ctx, conn, adapterID, adapterLabel, store, resolver, patterns, sessionID, tag, opts, sched)
+		}
 	}
 
-	queue := make(chan string, cfg.ConnectQueueSize)
-	doneCh := make(chan string, cfg.ConnectQueueSize)
-	for i := 0; i < maxConnect; i++ {
-		go bluezConnectWorker(ctx, conn, adapterID, adapterLabel, store, resolver, patterns, sessionID, tag, queue, doneCh)
+	// Per-adapter RSSI statistics, keyed by controller address.
+	obsAddr := adapterAddr
+	if obsAddr == "" {
+		obsAddr = adapterID
 	}
+	obs := newAdapterObservations()
+	go func() {
+		err := watchRSSI(ctx, conn, adapterID, func(u rssiUpdate) {
+			obs.add(u)
+			capture.sample(u, obsAddr, adapterLabel)
+		})
+		if err != nil && ctx.Err() == nil {
+			util.Linef("[WARN]", util.ColorYellow, "adapter=%s RSSI updates unavailable: %v", adapterID, err)
+		}
+	}()
+	lastObsFlush := time.Now()
+	defer func() { _ = obs.flush(store, sessionID, obsAddr, adapterLabel) }()
 
 	known := make(map[string]bool, 8192)
-	inFlight := make(map[string]bool, 8192)
-	lastConnAttempt := make(map[string]time.Time, 8192)
+	lastRefreshCheck := make(map[string]time.Time, 8192)
 	seenCount := make(map[string]int, 8192)
 
 	lastDeviceWrite := make(map[string]time.Time, 8192)
@@ -188,6 +287,8 @@ func runBlueZDiscoveryLoop(
 	lastGPSWrite := make(map[string]time.Time, 8192)
 	lastGPSVal := make(map[string]string, 8192)
 	lastMarked := make(map[string]string, 8192)
+	lastSensorWrite := make(map[string]time.Time, 8192)
+	lastSensorSig := make(map[string]string, 8192)
 
 	ticker := time.NewTicker(cfg.SnapshotInterval)
 	defer ticker.Stop()
@@ -199,17 +300,6 @@ func runBlueZDiscoveryLoop(
 		case <-ticker.C:
 		}
 
-		// Drain completed connect jobs.
-		for {
-			select {
-			case mac := <-doneCh:
-				delete(inFlight, mac)
-			default:
-				goto drained
-			}
-		}
-	drained:
-
 		// Snapshot all known devices under this adapter.
 		snap, err := bluezSnapshotWithConn(ctx, conn, adapterID)
 		if err != nil {
@@ -222,9 +312,18 @@ func runBlueZDiscoveryLoop(
 		}
 
 		now := time.Now()
+		if now.Sub(lastObsFlush) >= cfg.ObservationFlush {
+			lastObsFlush = now
+			if err := obs.flush(store, sessionID, obsAddr, adapterLabel); err != nil {
+				log.Printf("adapter observations %s: %v", adapterID, err)
+			}
+		}
 		if blacklist != nil {
 			blacklist.MaybeReload()
 		}
+		sched.MaybeReload()
+		capture.MaybeReload()
+		maybeReloadData(resolver, patterns)
 		for mac, bd := range snap {
 			select {
 			case <-ctx.Done():
@@ -240,9 +339,14 @@ func runBlueZDiscoveryLoop(
 			seenCount[mac]++
 
 			name := util.SafeName(bd.Name)
+
+			// Decoded manufacturer/service payloads (Apple Continuity, ...).
+			decoded := decodeAdvertisement(bd.ManufacturerEntries, bd.ServiceDataEntries)
+			devClass := describeDeviceClass(resolver, bd.Appearance, bd.Class)
+
 			if !known[mac] {
 				known[mac] = true
-				util.Linef("[NEW]", util.ColorGreen, "%s (Interface: %s) RSSI: %s", name, adapterID, rssiStr(bd.RSSI))
+				util.Linef("[NEW]", util.ColorGreen, "%s (Interface: %s) RSSI: %s%s%s", name, adapterID, rssiStr(bd.RSSI), productSuffix(decoded.Product), devClass.classSuffix())
 			} else {
 				// Update spam control: only print when we actually write an update.
 			}
@@ -276,18 +380,27 @@ func runBlueZDiscoveryLoop(
 					macType = "random"
 				}
 			}
+			if macType != "random" {
+				// Public address with the U/L or I/G bit set: not IEEE assigned.
+				if mv := resolver.LookupMAC(mac); mv.Multicast {
+					macSub = "multicast"
+				} else if mv.LocallyAdministered {
+					macSub = "locally_administered"
+				}
+			}
 
-			// Vendor from OUI (MA-L). This may be empty for random/private addresses.
+			// Vendor from IEEE registries (longest prefix). Empty for random/private addresses.
 			var vendor *string
 			if resolver != nil {
-				if v := strings.TrimSpace(resolver.VendorForMAC(mac)); v != "" {
+				if v := strings.TrimSpace(resolver.VendorForAddress(mac, macType == "random")); v != "" {
 					vv := v
 					vendor = &vv
 				}
 			}
 
 			// Structured manufacturer/service data.
-			mfgEntries := bd.ManufacturerEntries
+			mfgEntries := annotateManufacturerEntries(resolver, bd.ManufacturerEntries)
+			vendor = manufacturerNameFallback(vendor, macType == "random", mfgEntries)
 			svcEntries := bd.ServiceDataEntries
 			serviceUUIDs := annotateUUIDs(resolver, bd.UUIDs)
 
@@ -295,10 +408,27 @@ func runBlueZDiscoveryLoop(
 			svcUUIDJSON := jsonOrEmptyArray(serviceUUIDs)
 			svcDataJSON := jsonOrEmptyArray(svcEntries)
 
-			advJSON := buildAdvertisementJSONBlueZ(adapterID, adapterLabel, bd, name, serviceUUIDs, mfgEntries, svcEntries)
+			advJSON := buildAdvertisementJSONBlueZ(adapterID, adapterLabel, bd, name, serviceUUIDs, mfgEntries, svcEntries, decoded)
+			product := strPtrIfNotEmpty(decoded.Product)
+			if product == nil {
+				// Device ID (modalias) product for classic/dual-mode devices.
+				if mi, ok := describeModalias(resolver, bd.Modalias); ok {
+					product = strPtrIfNotEmpty(mi.ProductName)
+				}
+			}
 
-			// Special marker detection (e.g., Coke-ON) from raw UUIDs + manufacturer data.
-			markedTypeStr := DetectTypedDevice(patterns, bd.UUIDs, mfgEntries, bd.Name)
+			// Special marker detection (e.g., Coke-ON); all matching types, comma-separated.
+			markers := MatchDeviceTypes(patterns, PatternInput{
+				ServiceUUIDs: bd.UUIDs,
+				ServiceData:  svcEntries,
+				Manufacturer: mfgEntries,
+				Name:         bd.Name,
+				AddressType:  macType,
+				TxPower:      atoiPtr(bd.TxPower),
+				RSSI:         bd.RSSI,
+			})
+			markedTypeStr := markerNames(markers)
+			capture.consider(mac, name, markedTypeStr, atoiPtr(bd.TxPower))
 
 			// Throttle full device writes.
 			if last, ok := lastDeviceWrite[mac]; ok && now.Sub(last) < cfg.DeviceUpdateMinPeriod {
@@ -326,6 +456,7 @@ func runBlueZDiscoveryLoop(
 					if prev, ok := lastMarked[mac]; !ok || prev != mt {
 						lastMarked[mac] = mt
 						util.Linef("[MARK]", util.ColorCyan, "%s (%s) type=%s", name, mac, mt)
+						storeMarkers(ctx, store, sessionID, mac, ts, markers)
 					}
 					_ = store.UpdateDeviceMarkedType(ctx, mac, mt)
 				}
@@ -371,11 +502,20 @@ func runBlueZDiscoveryLoop(
 					TxPower:           bd.TxPower,
 					PlatformData:      bd.PropsJSON,
 					AdvertisementJSON: advJSON,
+					Product:           product,
+					Appearance:        devClass.Appearance,
+					AppearanceName:    strPtrIfNotEmpty(devClass.AppearanceName),
+					ClassOfDevice:     strPtrIfNotEmpty(devClass.ClassOfDeviceString()),
 					GPS:               gpsStr,
 					UpdateExisting:    true,
 					Tag:               tag,
 				})
 
+				// Beacon frames (iBeacon/Eddystone/AltBeacon).
+				if len(decoded.Beacons) > 0 {
+					storeBeacons(ctx, store, sessionID, mac, ts, bd.RSSI, decoded.Beacons)
+				}
+
 				// Marker type update.
 				if strings.TrimSpace(markedTypeStr) != "" {
 					mt := strings.TrimSpace(markedTypeStr)
@@ -384,6 +524,7 @@ func runBlueZDiscoveryLoop(
 						util.Linef("[MARK]", util.ColorCyan, "%s (%s) type=%s", name, mac, mt)
 					}
 					_ = store.UpdateDeviceMarkedType(ctx, mac, mt)
+					storeMarkers(ctx, store, sessionID, mac, ts, markers)
 				}
 			}
 
@@ -407,6 +548,19 @@ func runBlueZDiscoveryLoop(
 				}
 			}
 
+			// Sensor readings: write when values change (at most every SensorInsertMinPeriod),
+			// otherwise repeat every SensorRepeatPeriod so the series keeps a heartbeat.
+			if len(decoded.Readings) > 0 {
+				sig := readingsSignature(decoded.Readings)
+				last, ok := lastSensorWrite[mac]
+				changed := lastSensorSig[mac] != sig
+				if !ok || (changed && now.Sub(last) >= cfg.SensorInsertMinPeriod) || now.Sub(last) >= cfg.SensorRepeatPeriod {
+					lastSensorWrite[mac] = now
+					lastSensorSig[mac] = sig
+					storeSensorReadings(ctx, store, sessionID, mac, ts, decoded.Readings)
+				}
+			}
+
 			// Classic supplemental tables (best-effort) when device is likely BR/EDR.
 			if bd.isClassicLikely() {
 				if last, ok := lastClassicHist[mac]; !ok || now.Sub(last) >= cfg.ClassicHistMinPeriod {
@@ -425,24 +579,32 @@ func runBlueZDiscoveryLoop(
 					})
 				}
 
+				major, minor, services := classicCoDParams(bd.Class)
+				modVendor, modProduct := modaliasParams(resolver, bd.Modalias)
 				_ = store.UpsertClassicInfo(ctx, db.ClassicInfoParams{
-					MAC:           mac,
-					Class:         bd.Class,
-					Icon:          bd.Icon,
-					Paired:        bd.Paired,
-					Trusted:       bd.Trusted,
-					Connected:     bd.Connected,
-					Blocked:       bd.Blocked,
-					LegacyPairing: bd.LegacyPairing,
-					Modalias:      bd.Modalias,
-					UUIDsJSON:     bd.UUIDsJSON,
-					LastSeen:      &ts,
-					PropsJSON:     bd.PropsJSON,
+					MAC:             mac,
+					Class:           bd.Class,
+					Icon:            bd.Icon,
+					Paired:          bd.Paired,
+					Trusted:         bd.Trusted,
+					Connected:       bd.Connected,
+					Blocked:         bd.Blocked,
+					LegacyPairing:   bd.LegacyPairing,
+					Modalias:        bd.Modalias,
+					UUIDsJSON:       bd.UUIDsJSON,
+					LastSeen:        &ts,
+					PropsJSON:       bd.PropsJSON,
+					MajorClass:      major,
+					MinorClass:      minor,
+					ServiceClasses:  services,
+					ModaliasVendor:  modVendor,
+					ModaliasProduct: modProduct,
 				})
 			}
 
-			// Connection scheduling (BLE / dual only).
-			if devType == "classic" {
+			// Connection scheduling (BLE / dual only). Scan-only adapters still
+			// offer candidates for connect-only adapters; inquiry adapters do not.
+			if devType == "classic" || role == RoleClassic {
 				continue
 			}
 
@@ -459,25 +621,30 @@ func runBlueZDiscoveryLoop(
 				continue
 			}
 
-			hasGatt, _ := store.HasGattServices(ctx, mac)
-			if hasGatt {
+			// Already queued (sighting refreshed), connecting, or cooling down.
+			if sched.Touch(adapterID, mac, *bd.RSSI, now) {
 				continue
 			}
-			if inFlight[mac] {
+			// Re-interrogation policy (checked at most every RefreshCheckPeriod per MAC).
+			if last, ok := lastRefreshCheck[mac]; ok && now.Sub(last) < cfg.RefreshCheckPeriod {
 				continue
 			}
-			if last, ok := lastConnAttempt[mac]; ok && now.Sub(last) < cfg.ConnectCooldown {
+			lastRefreshCheck[mac] = now
+			st, serr := store.GattReadState(ctx, mac, sessionID)
+			if serr != nil {
 				continue
 			}
-			lastConnAttempt[mac] = now
-			inFlight[mac] = true
-
-			select {
-			case queue <- mac:
-				// queued
-			default:
-				delete(inFlight, mac)
+			due, reason := opts.Refresh.due(st, now)
+			if !due {
+				continue
 			}
+			if st.HasServices {
+				util.Linef("[REFRESH]", util.ColorGray, "%s (%s) %s", name, mac, reason)
+			}
+			sched.Offer(adapterID, mac, name, *bd.RSSI, ConnectPriority{
+				NeverConnected: !st.HasServices,
+				Marked:         st.MarkedType != "",
+			}, now)
 		}
 	}
 }
@@ -500,6 +667,35 @@ func bluezTypeToDeviceType(bd bluezDevice) string {
 	return "ble"
 }
 
+// annotateManufacturerEntries returns a copy of entries with SIG company names filled in.
+func annotateManufacturerEntries(resolver *ids.Resolver, entries []manufacturerEntry) []manufacturerEntry {
+	if resolver == nil || len(entries) == 0 {
+		return entries
+	}
+	out := make([]manufacturerEntry, len(entries))
+	for i, e := range entries {
+		if e.CompanyName == "" {
+			e.CompanyName = resolver.CompanyName(e.CompanyID)
+		}
+		out[i] = e
+	}
+	return out
+}
+
+// manufacturerNameFallback uses the first manufacturer data company name when
+// the OUI lookup found nothing or the address is random (OUI is meaningless there).
+func manufacturerNameFallback(vendor *string, random bool, mfg []manufacturerEntry) *string {
+	if vendor != nil && !random {
+		return vendor
+	}
+	for _, e := range mfg {
+		if n := strings.TrimSpace(e.CompanyName); n != "" {
+			return &n
+		}
+	}
+	return vendor
+}
+
 func annotateUUIDs(resolver *ids.Resolver, uuids []string) []string {
 	if len(uuids) == 0 {
 		return []string{}
@@ -518,7 +714,7 @@ func annotateUUIDs(resolver *ids.Resolver, uuids []string) []string {
 	return out
 }
 
-func buildAdvertisementJSONBlueZ(adapterID string, adapterLabel string, bd bluezDevice, name string, serviceUUIDs []string, mfg []manufacturerEntry, svc []serviceDataEntry) *string {
+func buildAdvertisementJSONBlueZ(adapterID string, adapterLabel string, bd bluezDevice, name string, serviceUUIDs []string, mfg []manufacturerEntry, svc []serviceDataEntry, decoded decode.Result) *string {
 	payload := map[string]any{
 		"source":        "bluez",
 		"adapter":       adapterID,
@@ -546,6 +742,15 @@ func buildAdvertisementJSONBlueZ(adapterID string, adapterLabel string, bd bluez
 	if bd.Icon != nil {
 		payload["icon"] = *bd.Icon
 	}
+	if bd.Appearance != nil {
+		payload["appearance"] = *bd.Appearance
+	}
+	if !decoded.Empty() {
+		payload["decoded"] = decoded.Frames
+	}
+	if decoded.Product != "" {
+		payload["product"] = decoded.Product
+	}
 	if b, err := json.Marshal(payload); err == nil {
 		s := string(b)
 		return &s
@@ -553,6 +758,15 @@ func buildAdvertisementJSONBlueZ(adapterID string, adapterLabel string, bd bluez
 	return nil
 }
 
+// productSuffix formats a derived product for console lines.
+func productSuffix(product string) string {
+	product = strings.TrimSpace(product)
+	if product == "" {
+		return ""
+	}
+	return " product=" + product
+}
+
 func rssiStr(rssi *int) string {
 	if rssi == nil {
 		return "n/a"
@@ -570,30 +784,29 @@ func bluezConnectWorker(
 	patterns *DeviceTypePatterns,
 	sessionID int64,
 	tag *string,
-	queue <-chan string,
-	doneCh chan<- string,
+	opts ConnectOptions,
+	sched *ConnectScheduler,
 ) {
 	for {
-		select {
-		case <-ctx.Done():
+		mac, ok := sched.Next(ctx, adapterID)
+		if !ok {
 			return
-		case mac := <-queue:
-			if strings.TrimSpace(mac) == "" {
-				continue
-			}
-			jobCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
-			err := ConnectAndDumpGATTBlueZ(jobCtx, conn, adapterID, adapterLabel, mac, store, resolver, patterns, sessionID, tag)
-			cancel()
-			if err != nil {
-				// Best-effort: do not spam logs for common transient issues.
-				es := err.Error()
-				if !strings.Contains(es, "UnknownObject") && !strings.Contains(es, "NotAvailable") {
-					log.Printf("bluez connect %s (%s) error: %v", adapterID, mac, err)
-				}
-			}
-			select {
-			case doneCh <- mac:
-			default:
+		}
+		started := time.Now()
+		jobCtx, cancel := context.WithTimeout(ctx, opts.jobTimeout())
+		err := ConnectAndDumpGATTBlueZ(jobCtx, conn, adapterID, adapterLabel, mac, store, resolver, patterns, sessionID, tag, opts)
+		cancel()
+		if err != nil && ctx.Err() != nil {
+			// Shutdown or adapter loss, not the device's fault.
+			err = ctx.Err()
+		}
+		class := classifyConnectError(err)
+		sched.Done(adapterID, mac, class)
+		recordConnectAttempt(store, sessionID, mac, adapterLabel, started, time.Now(), err, class)
+		if err != nil {
+			// Best-effort: do not spam logs for common transient issues (they are still stored).
+			if class != ConnErrUnknownObject && class != ConnErrNotAvailable && class != ConnErrCanceled {
+				log.Printf("bluez connect %s (%s) %s: %v", adapterID, mac, class, err)
 			}
 		}
 	}
@@ -611,6 +824,7 @@ func ConnectAndDumpGATTBlueZ(
 	patterns *DeviceTypePatterns,
 	sessionID int64,
 	tag *string,
+	opts ConnectOptions,
 ) error {
 	mac = strings.ToUpper(strings.TrimSpace(mac))
 	if mac == "" {
@@ -621,7 +835,12 @@ func ConnectAndDumpGATTBlueZ(
 	devObj := conn.Object("org.bluez", devPath)
 
 	// Connect.
-	if err := devObj.CallWithContext(ctx, "org.bluez.Device1.Connect", 0).Err; err != nil {
+	if _, known := bluezDeviceBoolProp(conn, devPath, "Connected"); !known {
+		// Not discovered by this adapter (connect-only role): let BlueZ create it.
+		if err := bluezConnectDevice(ctx, conn, adapterID, mac); err != nil {
+			return err
+		}
+	} else if err := devObj.CallWithContext(ctx, "org.bluez.Device1.Connect", 0).Err; err != nil {
 		return err
 	}
 	defer func() { _ = devObj.Call("org.bluez.Device1.Disconnect", 0).Err }()
@@ -645,15 +864,50 @@ func ConnectAndDumpGATTBlueZ(
 	}
 
 	// Dump and store characteristic-level data (flags + readable values).
-	servicesText, devName, derr := DumpAndStoreGATT(ctx, conn, adapterID, devPath, mac, store, resolver)
+	servicesText, devName, attrs, derr := DumpAndStoreGATT(ctx, conn, adapterID, devPath, mac, store, resolver, sessionID, opts.Reads)
 	if derr != nil {
 		return derr
 	}
 
+	// Opt-in pairing for allow-listed devices with protected characteristics.
+	if p := opts.Pairing; p != nil && p.Allow.Match(devName, mac) {
+		if before := countAuthErrors(attrs); before > 0 {
+			outcome, perr := p.pairDevice(ctx, conn, devPath)
+			var after *int
+			if perr == nil {
+				// Re-read everything now that the link is encrypted.
+				if text, name, a, err := DumpAndStoreGATT(ctx, conn, adapterID, devPath, mac, store, resolver, sessionID, opts.Reads); err == nil {
+					servicesText, devName, attrs = text, name, a
+					n := countAuthErrors(attrs)
+					after = &n
+				}
+			}
+			removed := false
+			if p.RemoveBond && outcome == "paired" {
+				// Runs before the deferred Disconnect; RemoveDevice disconnects as well.
+				defer func() {
+					if err := removeBond(conn, adapterID, devPath); err != nil {
+						log.Printf("bluez remove bond %s (%s) error: %v", adapterID, mac, err)
+					}
+				}()
+				removed = true
+			}
+			recordPairingAttempt(store, sessionID, mac, adapterLabel, outcome, perr, before, after, removed)
+		}
+	}
+
+	// Optionally listen to notify/indicate characteristics before disconnecting.
+	if opts.NotifyWindow > 0 {
+		if _, err := captureGATTNotifications(ctx, conn, devPath, mac, opts.NotifyWindow, store, sessionID); err != nil {
+			log.Printf("bluez notify %s (%s) error: %v", adapterID, mac, err)
+		}
+	}
+
 	// Save to DB.
 	ts := util.NowTimestamp()
-	_ = store.UpdateGattServices(ctx, mac, servicesText)
+	_ = store.UpdateGattServices(ctx, mac, servicesText, ts)
 	_ = store.InsertGattServicesHistory(ctx, sessionID, mac, servicesText, ts)
+	storeGattFingerprint(ctx, store, sessionID, mac, ts, attrs)
 
 	nameCopy := util.SafeName(devName)
 	adapterCopy := adapterLabel
@@ -682,6 +936,35 @@ func deviceObjectPath(adapterID, mac string) dbus.ObjectPath {
 	return dbus.ObjectPath("/org/bluez/" + adapterID + "/dev_" + m)
 }
 
+// bluezConnectDevice creates and connects a device the adapter has not
+// discovered itself (Adapter1.ConnectDevice). The address type is taken from
+// another adapter's object for the same MAC.
+func bluezConnectDevice(ctx context.Context, conn *dbus.Conn, adapterID, mac string) error {
+	addrType := "public"
+	root := conn.Object("org.bluez", dbus.ObjectPath("/"))
+	var managed map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	if err := root.CallWithContext(ctx, "org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&managed); err == nil {
+		for _, ifaces := range managed {
+			dev1, ok := ifaces["org.bluez.Device1"]
+			if !ok {
+				continue
+			}
+			if a, _ := getString(dev1, "Address"); !strings.EqualFold(strings.TrimSpace(a), mac) {
+				continue
+			}
+			if t, ok := getString(dev1, "AddressType"); ok && strings.TrimSpace(t) != "" {
+				addrType = strings.TrimSpace(t)
+				break
+			}
+		}
+	}
+	adapter := conn.Object("org.bluez", dbus.ObjectPath("/org/bluez/"+adapterID))
+	return adapter.CallWithContext(ctx, "org.bluez.Adapter1.ConnectDevice", 0, map[string]dbus.Variant{
+		"Address":     dbus.MakeVariant(mac),
+		"AddressType": dbus.MakeVariant(addrType),
+	}).Err
+}
+
 func bluezDeviceServicesResolved(ctx context.Context, conn *dbus.Conn, devPath dbus.ObjectPath) (bool, bool) {
 	root := conn.Object("org.bluez", dbus.ObjectPath("/"))
 	call := root.CallWithContext(ctx, "org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0)
@@ -7,20 +7,23 @@ import (
 	"fmt"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/godbus/dbus/v5"
 
 	"pible/internal/db"
+	"pible/internal/decode"
 	"pible/internal/ids"
 	"pible/internal/util"
 )
 
 // DumpAndStoreGATT reads as much as possible from BlueZ GATT objects:
 // - services
-// - characteristics (UUID, handle, flags, readable values)
+// - characteristics (UUID, handle, flags, readable values, decoded standard values)
 // - descriptors (UUID, handle, flags, readable values)
-// It returns a human-readable text dump and the best-effort device name.
+// Device Information Service values are also stored on the device row.
+// Reads are limited by opts; long values are read in MTU-sized chunks.
+// It returns a human-readable text dump, the best-effort device name and the
+// attribute list used for GATT fingerprints.
 func DumpAndStoreGATT(
 	ctx context.Context,
 	conn *dbus.Conn,
@@ -29,15 +32,17 @@ func DumpAndStoreGATT(
 	mac string,
 	store *db.Store,
 	resolver *ids.Resolver,
-) (string, string, error) {
+	sessionID int64,
+	opts GattReadOptions,
+) (string, string, []GattAttribute, error) {
 	root := conn.Object("org.bluez", dbus.ObjectPath("/"))
 	call := root.CallWithContext(ctx, "org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0)
 	if call.Err != nil {
-		return "", "", call.Err
+		return "", "", nil, call.Err
 	}
 	var managed map[dbus.ObjectPath]map[string]map[string]dbus.Variant
 	if err := call.Store(&managed); err != nil {
-		return "", "", err
+		return "", "", nil, err
 	}
 
 	// Best-effort name.
@@ -84,7 +89,7 @@ func DumpAndStoreGATT(
 		services = append(services, svcItem{path: path, uuid: uuid, handle: h})
 	}
 	if len(services) == 0 {
-		return "", name, errors.New("no GATT services")
+		return "", name, nil, errors.New("no GATT services")
 	}
 	sort.Slice(services, func(i, j int) bool { return string(services[i].path) < string(services[j].path) })
 
@@ -92,10 +97,9 @@ func DumpAndStoreGATT(
 	now := util.NowTimestamp()
 
 	// Limits to prevent pathological devices from stalling the worker.
-	// Values are tuned for "lots of devices" environments.
-	const maxCharsToRead = 40
-	const perReadTimeout = 900 * time.Millisecond
-	readCount := 0
+	reader := newGattReader(conn, opts)
+	var info db.DeviceInfoParams
+	attrs := make([]GattAttribute, 0, 128)
 
 	for _, s := range services {
 		svcUUIDAnn := s.uuid
@@ -103,6 +107,7 @@ func DumpAndStoreGATT(
 			svcUUIDAnn = resolver.AnnotateServiceUUID(svcUUIDAnn)
 		}
 		lines = append(lines, fmt.Sprintf("Service: %s", svcUUIDAnn))
+		attrs = append(attrs, newGattAttribute("service", s.uuid, "", s.uuid, s.handle, nil))
 
 		// Collect characteristics under this service.
 		type chItem struct {
@@ -110,6 +115,7 @@ func DumpAndStoreGATT(
 			uuid   string
 			handle *uint16
 			flags  []string
+			mtu    *uint16
 		}
 		chars := make([]chItem, 0, 32)
 		for path, ifaces := range managed {
@@ -128,7 +134,7 @@ func DumpAndStoreGATT(
 			}
 			h := getUint16Ptr(ch, "Handle")
 			flags := getStringSlice(ch, "Flags")
-			chars = append(chars, chItem{path: path, uuid: uuid, handle: h, flags: flags})
+			chars = append(chars, chItem{path: path, uuid: uuid, handle: h, flags: flags, mtu: getUint16Ptr(ch, "MTU")})
 		}
 		sort.Slice(chars, func(i, j int) bool { return string(chars[i].path) < string(chars[j].path) })
 
@@ -149,10 +155,14 @@ func DumpAndStoreGATT(
 			lines = append(lines, fmt.Sprintf("  │  Properties: %s", flagsStr))
 
 			// Read value when allowed and within limits.
-			var valHex, valASCII, readErrStr *string
-			if readCount < maxCharsToRead && hasFlag(c.flags, "read") {
-				readCount++
-				v, rerr := readCharacteristic(ctx, conn, c.path, perReadTimeout)
+			var valHex, valASCII, valDecoded, readErrStr *string
+			truncated := false
+			if limit := reader.limit(); limit != "" && hasFlag(c.flags, "read") {
+				lines = append(lines, fmt.Sprintf("  │  Value: (skipped; %s)", limit))
+			} else if hasFlag(c.flags, "read") {
+				reader.reads++
+				v, trunc, rerr := reader.readValue(ctx, c.path, "org.bluez.GattCharacteristic1", c.mtu)
+				truncated = trunc
 				if rerr != nil {
 					e := rerr.Error()
 					readErrStr = &e
@@ -161,28 +171,49 @@ func DumpAndStoreGATT(
 					h := util.BytesToHex(v)
 					valHex = &h
 					lines = append(lines, fmt.Sprintf("  │  Value(hex): %s", h))
+					if truncated {
+						lines = append(lines, fmt.Sprintf("  │  Value: (truncated after %d bytes)", len(v)))
+					}
 					if s := asciiIfPrintable(v); s != "" {
 						valASCII = &s
 						lines = append(lines, fmt.Sprintf("  │  Value(ascii): %s", s))
 					}
+					if d := decodeCharValue(resolver, c.uuid, v, &info); d != nil {
+						valDecoded = d
+						lines = append(lines, fmt.Sprintf("  │  Value(decoded): %s", *d))
+					}
 				}
-			} else if readCount >= maxCharsToRead && hasFlag(c.flags, "read") {
-				lines = append(lines, "  │  Value: (skipped; read limit reached)")
 			}
 
+			ca := newGattAttribute("characteristic", s.uuid, "", c.uuid, c.handle, c.flags)
+			if valHex != nil {
+				ca.Value = *valHex
+			}
+			if valASCII != nil {
+				ca.Text = *valASCII
+			}
+			if readErrStr != nil {
+				ca.ReadError = *readErrStr
+			}
+			attrs = append(attrs, ca)
+
 			// Persist characteristic.
 			if store != nil {
 				flagsJSON := sliceToJSON(c.flags)
 				_ = store.UpsertGattCharacteristic(ctx, db.GattCharacteristicParams{
+					SessionID:     &sessionID,
 					MAC:           mac,
 					ServiceUUID:   s.uuid,
 					ServiceHandle: s.handle,
 					CharUUID:      c.uuid,
 					CharHandle:    c.handle,
 					FlagsJSON:     flagsJSON,
+					MTU:           c.mtu,
 					ValueHex:      valHex,
 					ValueASCII:    valASCII,
+					ValueDecoded:  valDecoded,
 					ReadError:     readErrStr,
+					Truncated:     truncated,
 					LastReadAt:    now,
 				})
 			}
@@ -227,8 +258,11 @@ func DumpAndStoreGATT(
 				lines = append(lines, fmt.Sprintf("  │    Properties: %s", flagsStr))
 
 				var vHex, vASCII, rErr *string
-				if hasFlag(d.flags, "read") {
-					v, rerr := readDescriptor(ctx, conn, d.path, perReadTimeout)
+				if limit := reader.limit(); limit != "" && hasFlag(d.flags, "read") {
+					lines = append(lines, fmt.Sprintf("  │    Value: (skipped; %s)", limit))
+				} else if hasFlag(d.flags, "read") {
+					reader.reads++
+					v, _, rerr := reader.readValue(ctx, d.path, "org.bluez.GattDescriptor1", nil)
 					if rerr != nil {
 						e := rerr.Error()
 						rErr = &e
@@ -244,6 +278,18 @@ func DumpAndStoreGATT(
 					}
 				}
 
+				da := newGattAttribute("descriptor", s.uuid, c.uuid, d.uuid, d.handle, d.flags)
+				if vHex != nil {
+					da.Value = *vHex
+				}
+				if vASCII != nil {
+					da.Text = *vASCII
+				}
+				if rErr != nil {
+					da.ReadError = *rErr
+				}
+				attrs = append(attrs, da)
+
 				if store != nil {
 					flagsJSON := sliceToJSON(d.flags)
 					_ = store.UpsertGattDescriptor(ctx, db.GattDescriptorParams{
@@ -265,7 +311,70 @@ func DumpAndStoreGATT(
 		}
 	}
 
-	return strings.Join(lines, "\n"), name, nil
+	if store != nil && !info.Empty() {
+		_ = store.UpdateDeviceInfo(ctx, mac, info)
+	}
+
+	return strings.Join(lines, "\n"), name, attrs, nil
+}
+
+// decodeCharValue decodes a standard characteristic value to JSON and
+// collects Device Information Service fields into info.
+func decodeCharValue(resolver *ids.Resolver, uuid string, v []byte, info *db.DeviceInfoParams) *string {
+	fields, ok := decode.Characteristic(uuid, v)
+	if !ok {
+		return nil
+	}
+	u16, _ := decode.UUID16(uuid)
+	str := func() *string {
+		s, _ := fields["value"].(string)
+		return strPtrIfNotEmpty(s)
+	}
+	switch u16 {
+	case decode.CharManufacturerName:
+		info.Manufacturer = str()
+	case decode.CharModelNumber:
+		info.Model = str()
+	case decode.CharSerialNumber:
+		info.Serial = str()
+	case decode.CharFirmwareRevision:
+		info.FirmwareRevision = str()
+	case decode.CharHardwareRevision:
+		info.HardwareRevision = str()
+	case decode.CharSoftwareRevision:
+		info.SoftwareRevision = str()
+	case decode.CharSystemID:
+		s := fmt.Sprintf("%s-%s", fields["oui"], fields["manufacturer_id"])
+		info.SystemID = &s
+	case decode.CharPnPID:
+		// Same notation as the BlueZ Modalias property.
+		src := ids.VendorSourceBluetooth
+		if v[0] == 2 {
+			src = ids.VendorSourceUSB
+		}
+		ver := uint16(v[5]) | uint16(v[6])<<8
+		m := ids.Modalias{Source: src, Vendor: uint16(v[1]) | uint16(v[2])<<8, Product: uint16(v[3]) | uint16(v[4])<<8, Version: &ver}
+		s := m.String()
+		info.PnPID = &s
+		if name := resolver.ModaliasVendor(m); name != "" {
+			fields["vendor"] = name
+		}
+		if name := resolver.ModaliasProduct(m); name != "" {
+			fields["product"] = name
+		}
+	case decode.CharAppearance:
+		if n, ok := fields["value"].(int); ok {
+			if name := resolver.AppearanceName(uint16(n)); name != "" {
+				fields["name"] = name
+			}
+		}
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	s := string(b)
+	return &s
 }
 
 func getUint16Ptr(props map[string]dbus.Variant, key string) *uint16 {
@@ -339,33 +448,3 @@ func hasFlag(flags []string, want string) bool {
 	}
 	return false
 }
-
-func readCharacteristic(ctx context.Context, conn *dbus.Conn, chPath dbus.ObjectPath, timeout time.Duration) ([]byte, error) {
-	obj := conn.Object("org.bluez", chPath)
-	readCtx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-	call := obj.CallWithContext(readCtx, "org.bluez.GattCharacteristic1.ReadValue", 0, map[string]dbus.Variant{})
-	if call.Err != nil {
-		return nil, call.Err
-	}
-	var out []byte
-	if err := call.Store(&out); err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func readDescriptor(ctx context.Context, conn *dbus.Conn, dPath dbus.ObjectPath, timeout time.Duration) ([]byte, error) {
-	obj := conn.Object("org.bluez", dPath)
-	readCtx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-	call := obj.CallWithContext(readCtx, "org.bluez.GattDescriptor1.ReadValue", 0, map[string]dbus.Variant{})
-	if call.Err != nil {
-		return nil, call.Err
-	}
-	var out []byte
-	if err := call.Store(&out); err != nil {
-		return nil, err
-	}
-	return out, nil
-}
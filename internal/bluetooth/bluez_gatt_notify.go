@@ -0,0 +1,177 @@
+package bluetooth
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"pible/internal/db"
+	"pible/internal/util"
+)
+
+// captureGATTNotifications subscribes to every notify/indicate characteristic
+// of a connected device, collects the values BlueZ reports for window and
+// stores them in gatt_notifications. Subscriptions are stopped before return.
+// It returns the number of stored values.
+func captureGATTNotifications(
+	ctx context.Context,
+	conn *dbus.Conn,
+	devPath dbus.ObjectPath,
+	mac string,
+	window time.Duration,
+	store *db.Store,
+	sessionID int64,
+) (int, error) {
+	// Limits to keep a chatty device from holding the worker.
+	const maxCharsToNotify = 16
+	const maxValues = 2000
+	const perCallTimeout = 2 * time.Second
+
+	root := conn.Object("org.bluez", dbus.ObjectPath("/"))
+	call := root.CallWithContext(ctx, "org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0)
+	if call.Err != nil {
+		return 0, call.Err
+	}
+	var managed map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	if err := call.Store(&managed); err != nil {
+		return 0, err
+	}
+
+	type notifyChar struct {
+		path    dbus.ObjectPath
+		uuid    string
+		svcUUID string
+	}
+	devPrefix := string(devPath) + "/"
+	chars := make([]notifyChar, 0, 16)
+	for path, ifaces := range managed {
+		if !strings.HasPrefix(string(path), devPrefix) {
+			continue
+		}
+		ch, ok := ifaces["org.bluez.GattCharacteristic1"]
+		if !ok {
+			continue
+		}
+		flags := getStringSlice(ch, "Flags")
+		if !hasFlag(flags, "notify") && !hasFlag(flags, "indicate") {
+			continue
+		}
+		uuid, _ := getString(ch, "UUID")
+		uuid = strings.TrimSpace(uuid)
+		if uuid == "" {
+			continue
+		}
+		svcUUID := ""
+		if svcPath, ok := getObjectPath(ch, "Service"); ok {
+			if svc, ok := managed[svcPath]["org.bluez.GattService1"]; ok {
+				svcUUID, _ = getString(svc, "UUID")
+			}
+		}
+		chars = append(chars, notifyChar{path: path, uuid: uuid, svcUUID: strings.TrimSpace(svcUUID)})
+	}
+	if len(chars) == 0 {
+		return 0, nil
+	}
+	sort.Slice(chars, func(i, j int) bool { return string(chars[i].path) < string(chars[j].path) })
+	if len(chars) > maxCharsToNotify {
+		chars = chars[:maxCharsToNotify]
+	}
+
+	// Listen before StartNotify so the first values are not missed.
+	matchOpts := []dbus.MatchOption{
+		dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		dbus.WithMatchMember("PropertiesChanged"),
+		dbus.WithMatchPathNamespace(devPath),
+	}
+	if err := conn.AddMatchSignalContext(ctx, matchOpts...); err != nil {
+		return 0, err
+	}
+	defer func() { _ = conn.RemoveMatchSignal(matchOpts...) }()
+	sigCh := make(chan *dbus.Signal, 256)
+	conn.Signal(sigCh)
+	defer conn.RemoveSignal(sigCh)
+
+	started := make(map[dbus.ObjectPath]notifyChar, len(chars))
+	for _, c := range chars {
+		callCtx, cancel := context.WithTimeout(ctx, perCallTimeout)
+		err := conn.Object("org.bluez", c.path).CallWithContext(callCtx, "org.bluez.GattCharacteristic1.StartNotify", 0).Err
+		cancel()
+		if err != nil {
+			continue
+		}
+		started[c.path] = c
+	}
+	// StopNotify runs even if ctx has expired; BlueZ keeps the subscription otherwise.
+	defer func() {
+		for path := range started {
+			stopCtx, cancel := context.WithTimeout(context.Background(), perCallTimeout)
+			_ = conn.Object("org.bluez", path).CallWithContext(stopCtx, "org.bluez.GattCharacteristic1.StopNotify", 0).Err
+			cancel()
+		}
+	}()
+	if len(started) == 0 {
+		return 0, nil
+	}
+
+	rows := make([]db.GattNotificationParams, 0, 64)
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+collect:
+	for len(rows) < maxValues {
+		select {
+		case <-ctx.Done():
+			break collect
+		case <-timer.C:
+			break collect
+		case sig := <-sigCh:
+			if sig == nil || len(sig.Body) < 2 {
+				continue
+			}
+			c, ok := started[sig.Path]
+			if !ok {
+				continue
+			}
+			iface, _ := sig.Body[0].(string)
+			if iface != "org.bluez.GattCharacteristic1" {
+				continue
+			}
+			changed, _ := sig.Body[1].(map[string]dbus.Variant)
+			v, ok := changed["Value"]
+			if !ok {
+				continue
+			}
+			b, ok := v.Value().([]byte)
+			if !ok {
+				continue
+			}
+			rows = append(rows, db.GattNotificationParams{
+				ServiceUUID:  c.svcUUID,
+				CharUUID:     c.uuid,
+				Timestamp:    time.Now().Format("2006-01-02 15:04:05.000"),
+				ValueHex:     util.BytesToHex(b),
+				ValueDecoded: decodeCharValue(nil, c.uuid, b, &db.DeviceInfoParams{}),
+			})
+		}
+	}
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if err := store.InsertGattNotifications(context.Background(), &sessionID, mac, rows); err != nil {
+		return 0, err
+	}
+	util.Linef("[NOTIFY]", util.ColorCyan, "%s %d value(s) from %d characteristic(s) in %s", mac, len(rows), len(started), window)
+	return len(rows), nil
+}
+
+func getObjectPath(props map[string]dbus.Variant, key string) (dbus.ObjectPath, bool) {
+	v, ok := props[key]
+	if !ok {
+		return "", false
+	}
+	p, ok := v.Value().(dbus.ObjectPath)
+	return p, ok
+}
@@ -2,11 +2,13 @@ package bluetooth
 
 import (
 	"context"
+	"encoding/xml"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
 
 	"pible/internal/db"
 	"pible/internal/gps"
@@ -25,18 +27,17 @@ func runManagedAdapterLoop(
 	resolver *ids.Resolver,
 	patterns *DeviceTypePatterns,
 	sessionID int64,
-	maxConnect int,
+	sched *ConnectScheduler,
+	capture *RSSICapture,
 	tag *string,
 	blacklist *ConnectBlacklist,
+	scan ScanPlan,
+	opts ConnectOptions,
 ) {
 	adapterID = strings.TrimSpace(adapterID)
 	if adapterID == "" {
 		return
 	}
-	if maxConnect < 1 {
-		maxConnect = 1
-	}
-
 	conn, err := dbus.SystemBus()
 	if err != nil {
 		util.Linef("[ERROR]", util.ColorYellow, "dbus SystemBus failed: %v", err)
@@ -119,7 +120,7 @@ func runManagedAdapterLoop(
 			}
 		}()
 
-		_ = runBlueZDiscoveryLoop(workerCtx, adapterID, store, gpsState, resolver, patterns, sessionID, maxConnect, tag, blacklist)
+		_ = runBlueZDiscoveryLoop(workerCtx, adapterID, store, gpsState, resolver, patterns, sessionID, sched, capture, tag, blacklist, scan, opts)
 		cancel()
 		<-monDone
 
@@ -184,6 +185,32 @@ func bluezAdapterAddress(ctx context.Context, conn *dbus.Conn, adapterID string)
 	return ""
 }
 
+// bluezAdapterHasMethod reports whether the adapter's Adapter1 interface offers
+// method. Experimental methods such as ConnectDevice are only exported when
+// bluetoothd runs with -E (--experimental).
+func bluezAdapterHasMethod(ctx context.Context, conn *dbus.Conn, adapterID, method string) (bool, error) {
+	obj := conn.Object("org.bluez", dbus.ObjectPath("/org/bluez/"+strings.TrimSpace(adapterID)))
+	var data string
+	if err := obj.CallWithContext(ctx, "org.freedesktop.DBus.Introspectable.Introspect", 0).Store(&data); err != nil {
+		return false, err
+	}
+	var node introspect.Node
+	if err := xml.Unmarshal([]byte(data), &node); err != nil {
+		return false, err
+	}
+	for _, iface := range node.Interfaces {
+		if iface.Name != "org.bluez.Adapter1" {
+			continue
+		}
+		for _, m := range iface.Methods {
+			if m.Name == method {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 // bluezFindAdapterByAddress finds an adapter ID (e.g., hci0) by controller Address.
 func bluezFindAdapterByAddress(ctx context.Context, conn *dbus.Conn, addr string) string {
 	addr = strings.ToUpper(strings.TrimSpace(addr))
@@ -157,6 +157,7 @@ type bluezDevice struct {
 	ServiceDataEntries  []serviceDataEntry
 	Class         *uint32
 	Icon          *string
+	Appearance    *uint16
 	Paired        *bool
 	Trusted       *bool
 	Connected     *bool
@@ -251,6 +252,14 @@ func bluezSnapshotWithConn(ctx context.Context, conn *dbus.Conn, adapterID strin
 			}
 		}
 
+		var appearancePtr *uint16
+		if v, ok := dev1["Appearance"]; ok {
+			if a, ok2 := v.Value().(uint16); ok2 {
+				aa := a
+				appearancePtr = &aa
+			}
+		}
+
 		icon := getStringPtr(dev1, "Icon")
 		modalias := getStringPtr(dev1, "Modalias")
 
@@ -285,6 +294,7 @@ func bluezSnapshotWithConn(ctx context.Context, conn *dbus.Conn, adapterID strin
 			ServiceDataEntries:  svcEntries,
 			Class:         classPtr,
 			Icon:          icon,
+			Appearance:    appearancePtr,
 			Paired:        paired,
 			Trusted:       trusted,
 			Connected:     connected,
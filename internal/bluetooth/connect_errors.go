@@ -0,0 +1,123 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"pible/internal/db"
+)
+
+// Normalized connection error classes stored in connection_attempts.error_class.
+const (
+	ConnErrCanceled            = "canceled"
+	ConnErrTimeout             = "timeout"
+	ConnErrServicesNotResolved = "services_not_resolved"
+	ConnErrNoServices          = "no_services"
+	ConnErrAuthRequired        = "auth_required"
+	ConnErrAbortByRemote       = "aborted_by_remote"
+	ConnErrAbortByLocal        = "le_connection_abort_by_local"
+	ConnErrPageTimeout         = "page_timeout"
+	ConnErrUnknownObject       = "unknown_object"
+	ConnErrNotAvailable        = "not_available"
+	ConnErrInProgress          = "in_progress"
+	ConnErrNotReady            = "adapter_not_ready"
+	ConnErrRefused             = "refused"
+	ConnErrIO                  = "io_error"
+	ConnErrOther               = "other"
+)
+
+// DeviceErrorClasses are the error classes that point at the device itself
+// (out of range, unresponsive, rejecting us). Only these extend a device's
+// retry backoff; adapter-side or transient classes such as in_progress,
+// adapter_not_ready, unknown_object or not_available do not.
+var DeviceErrorClasses = []string{
+	ConnErrTimeout,
+	ConnErrServicesNotResolved,
+	ConnErrNoServices,
+	ConnErrAuthRequired,
+	ConnErrAbortByRemote,
+	ConnErrPageTimeout,
+}
+
+func isDeviceErrorClass(class string) bool {
+	for _, c := range DeviceErrorClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyConnectError maps BlueZ/D-Bus connection errors to a small set of
+// classes so failures can be counted per cause. nil maps to "".
+func classifyConnectError(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.Canceled) {
+		return ConnErrCanceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ConnErrTimeout
+	}
+	es := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(es, "services not resolved"):
+		return ConnErrServicesNotResolved
+	case strings.Contains(es, "no gatt services"):
+		return ConnErrNoServices
+	case strings.Contains(es, "le-connection-abort-by-local"):
+		return ConnErrAbortByLocal
+	case strings.Contains(es, "page-timeout"):
+		return ConnErrPageTimeout
+	case strings.Contains(es, "authentication"), strings.Contains(es, "notauthorized"),
+		strings.Contains(es, "not authorized"), strings.Contains(es, "insufficient encryption"),
+		strings.Contains(es, "notpermitted"), strings.Contains(es, "not permitted"):
+		return ConnErrAuthRequired
+	case strings.Contains(es, "abort-by-remote"), strings.Contains(es, "aborted by remote"),
+		strings.Contains(es, "connection reset by peer"), strings.Contains(es, "remote user terminated"):
+		return ConnErrAbortByRemote
+	case strings.Contains(es, "timeout"), strings.Contains(es, "timed out"),
+		strings.Contains(es, "did not receive a reply"):
+		return ConnErrTimeout
+	case strings.Contains(es, "unknownobject"), strings.Contains(es, "unknown object"):
+		return ConnErrUnknownObject
+	case strings.Contains(es, "notavailable"), strings.Contains(es, "not available"):
+		return ConnErrNotAvailable
+	case strings.Contains(es, "inprogress"), strings.Contains(es, "in progress"):
+		return ConnErrInProgress
+	case strings.Contains(es, "notready"), strings.Contains(es, "not ready"):
+		return ConnErrNotReady
+	case strings.Contains(es, "connection refused"):
+		return ConnErrRefused
+	case strings.Contains(es, "input/output error"), strings.Contains(es, "software caused connection abort"):
+		return ConnErrIO
+	}
+	return ConnErrOther
+}
+
+// recordConnectAttempt stores one connection attempt. It uses a background
+// context so attempts cut short by shutdown are still recorded.
+func recordConnectAttempt(store *db.Store, sessionID int64, mac, adapter string, started, ended time.Time, err error, class string) {
+	if store == nil {
+		return
+	}
+	p := db.ConnectionAttemptParams{
+		SessionID:  &sessionID,
+		MAC:        mac,
+		Adapter:    adapter,
+		StartedAt:  started.Format("2006-01-02 15:04:05"),
+		EndedAt:    ended.Format("2006-01-02 15:04:05"),
+		DurationMS: ended.Sub(started).Milliseconds(),
+		Outcome:    "ok",
+	}
+	if err != nil {
+		e := err.Error()
+		p.Outcome = "error"
+		p.ErrorClass = &class
+		p.Error = &e
+	}
+	_ = store.InsertConnectionAttempt(context.Background(), p)
+}
@@ -0,0 +1,397 @@
+package bluetooth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"pible/internal/db"
+	"pible/internal/status"
+)
+
+// ConnectSchedulerConfig holds the limits of a ConnectScheduler.
+type ConnectSchedulerConfig struct {
+	MaxTotal      int           // simultaneous connections over all adapters
+	MaxPerAdapter int           // simultaneous connections per adapter (0 = MaxTotal)
+	MaxPending    int           // queued candidates; the lowest priority is dropped beyond this
+	StaleAfter    time.Duration // drop candidates no adapter has seen for this long
+	Cooldown      time.Duration // minimum time between two attempts on the same MAC
+	MaxBackoff    time.Duration // cap of the cooldown after repeated failures (0 = Cooldown)
+	Watchlist     *DeviceList
+}
+
+// ConnectScheduler is the connection queue shared by all adapters.
+//
+// Candidates are offered by the discovery loops and handed to connect workers
+// in priority order:
+// - watchlist devices first
+// - then devices we have never connected to
+// - then devices with a marked type
+// - stronger RSSI first within the same class
+// A worker only receives candidates its adapter has seen recently, so an idle
+// adapter picks up devices a busy adapter also sees. Connect-only adapters,
+// which do not scan, receive candidates any adapter has seen recently.
+type ConnectScheduler struct {
+	cfg ConnectSchedulerConfig
+
+	mu          sync.Mutex
+	wake        chan struct{}
+	pending     map[string]*connectCandidate
+	inFlight    map[string]bool
+	lastAttempt map[string]time.Time
+	failures    map[string]int // failed attempts since the last success
+	active      map[string]int
+	activeTotal int
+	connectOnly map[string]bool // adapters that take candidates seen by any adapter
+
+	// Counters since the previous QueueStats call.
+	started      int
+	waitSum      time.Duration
+	waitMax      time.Duration
+	droppedStale int
+	droppedFull  int
+}
+
+// ConnectPriority describes why a candidate matters.
+type ConnectPriority struct {
+	NeverConnected bool
+	Marked         bool
+}
+
+type connectSighting struct {
+	rssi int
+	at   time.Time
+}
+
+type connectCandidate struct {
+	mac      string
+	name     string
+	prio     ConnectPriority
+	watch    bool
+	queuedAt time.Time
+	seen     map[string]connectSighting // adapter ID -> latest sighting
+}
+
+func NewConnectScheduler(cfg ConnectSchedulerConfig) *ConnectScheduler {
+	if cfg.MaxTotal < 1 {
+		cfg.MaxTotal = 1
+	}
+	if cfg.MaxPerAdapter < 1 || cfg.MaxPerAdapter > cfg.MaxTotal {
+		cfg.MaxPerAdapter = cfg.MaxTotal
+	}
+	if cfg.MaxPending < 1 {
+		cfg.MaxPending = 8192
+	}
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = 30 * time.Second
+	}
+	if cfg.MaxBackoff < cfg.Cooldown {
+		cfg.MaxBackoff = cfg.Cooldown
+	}
+	return &ConnectScheduler{
+		cfg:         cfg,
+		wake:        make(chan struct{}),
+		pending:     make(map[string]*connectCandidate, 1024),
+		inFlight:    make(map[string]bool, 64),
+		lastAttempt: make(map[string]time.Time, 8192),
+		failures:    make(map[string]int, 1024),
+		active:      make(map[string]int, 4),
+		connectOnly: make(map[string]bool, 4),
+	}
+}
+
+// MaxPerAdapter is the number of connect workers to start per adapter.
+func (s *ConnectScheduler) MaxPerAdapter() int {
+	return s.cfg.MaxPerAdapter
+}
+
+// SetConnectOnly marks adapterID as an adapter without discovery of its own.
+func (s *ConnectScheduler) SetConnectOnly(adapterID string, on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if on {
+		s.connectOnly[adapterID] = true
+	} else {
+		delete(s.connectOnly, adapterID)
+	}
+}
+
+// MaybeReload reloads the watchlist if it has changed.
+func (s *ConnectScheduler) MaybeReload() {
+	s.cfg.Watchlist.MaybeReload()
+}
+
+// Touch refreshes the sighting of a queued candidate. It returns true when the
+// MAC is queued, connecting or still in its cooldown, i.e. when offering it
+// again is pointless.
+func (s *ConnectScheduler) Touch(adapterID, mac string, rssi int, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight[mac] {
+		return true
+	}
+	if c, ok := s.pending[mac]; ok {
+		c.seen[adapterID] = connectSighting{rssi: rssi, at: now}
+		return true
+	}
+	if last, ok := s.lastAttempt[mac]; ok && now.Sub(last) < s.backoffLocked(mac) {
+		return true
+	}
+	return false
+}
+
+// LoadBackoff seeds the retry state from stored connection attempts so the
+// backoff survives restarts. It returns the number of devices in backoff.
+func (s *ConnectScheduler) LoadBackoff(ctx context.Context, store *db.Store) (int, error) {
+	entries, err := store.ListConnectBackoff(ctx, DeviceErrorClasses)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	now := time.Now()
+	for _, e := range entries {
+		last, err := time.ParseInLocation("2006-01-02 15:04:05", e.LastAttempt, time.Local)
+		if err != nil || e.Failures < 1 {
+			continue
+		}
+		s.failures[e.MAC] = e.Failures
+		if prev, ok := s.lastAttempt[e.MAC]; !ok || last.After(prev) {
+			s.lastAttempt[e.MAC] = last
+		}
+		if now.Sub(last) < s.backoffLocked(e.MAC) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// backoffLocked is the cooldown for a MAC: Cooldown, doubled for every
+// consecutive failure after the first, up to MaxBackoff.
+func (s *ConnectScheduler) backoffLocked(mac string) time.Duration {
+	d := s.cfg.Cooldown
+	for i := 1; i < s.failures[mac] && d < s.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.cfg.MaxBackoff {
+		d = s.cfg.MaxBackoff
+	}
+	return d
+}
+
+// Offer queues a candidate seen by adapterID.
+func (s *ConnectScheduler) Offer(adapterID, mac, name string, rssi int, prio ConnectPriority, now time.Time) {
+	watch := s.cfg.Watchlist.Match(name, mac)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight[mac] {
+		return
+	}
+	c, ok := s.pending[mac]
+	if !ok {
+		if len(s.pending) >= s.cfg.MaxPending && !s.dropLowestLocked(prio, watch, rssi) {
+			s.droppedFull++
+			return
+		}
+		c = &connectCandidate{mac: mac, queuedAt: now, seen: make(map[string]connectSighting, 2)}
+		s.pending[mac] = c
+	}
+	c.name = name
+	c.prio = prio
+	c.watch = watch
+	c.seen[adapterID] = connectSighting{rssi: rssi, at: now}
+	s.signalLocked()
+}
+
+// Next blocks until a candidate seen by adapterID can be connected, or ctx ends.
+func (s *ConnectScheduler) Next(ctx context.Context, adapterID string) (string, bool) {
+	for {
+		s.mu.Lock()
+		wake := s.wake
+		if s.activeTotal < s.cfg.MaxTotal && s.active[adapterID] < s.cfg.MaxPerAdapter {
+			if c := s.pickLocked(adapterID, time.Now()); c != nil {
+				now := time.Now()
+				delete(s.pending, c.mac)
+				s.inFlight[c.mac] = true
+				s.lastAttempt[c.mac] = now
+				s.active[adapterID]++
+				s.activeTotal++
+				wait := now.Sub(c.queuedAt)
+				s.started++
+				s.waitSum += wait
+				if wait > s.waitMax {
+					s.waitMax = wait
+				}
+				s.mu.Unlock()
+				return c.mac, true
+			}
+		}
+		s.mu.Unlock()
+
+		// Re-check periodically so stale entries are pruned without new offers.
+		select {
+		case <-ctx.Done():
+			return "", false
+		case <-wake:
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// Done releases the slot taken by Next. errClass is the attempt's error class
+// ("" on success): a success resets the MAC's backoff, a failure caused by the
+// device (DeviceErrorClasses) extends it and any other failure leaves it
+// unchanged.
+func (s *ConnectScheduler) Done(adapterID, mac string, errClass string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inFlight, mac)
+	switch {
+	case errClass == "":
+		delete(s.failures, mac)
+	case isDeviceErrorClass(errClass):
+		s.failures[mac]++
+	}
+	if s.active[adapterID] > 0 {
+		s.active[adapterID]--
+	}
+	if s.activeTotal > 0 {
+		s.activeTotal--
+	}
+	s.signalLocked()
+}
+
+// QueueStats implements status.QueueSource. Wait times and drop counters
+// cover the period since the previous call.
+func (s *ConnectScheduler) QueueStats() status.QueueStats {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(now)
+	st := status.QueueStats{
+		Pending:         len(s.pending),
+		Active:          s.activeTotal,
+		ActiveByAdapter: make(map[string]int, len(s.active)),
+		Started:         s.started,
+		MaxWait:         s.waitMax,
+		DroppedStale:    s.droppedStale,
+		DroppedFull:     s.droppedFull,
+	}
+	for a, n := range s.active {
+		st.ActiveByAdapter[a] = n
+	}
+	if s.started > 0 {
+		st.AvgWait = s.waitSum / time.Duration(s.started)
+	}
+	for _, c := range s.pending {
+		if w := now.Sub(c.queuedAt); w > st.OldestWait {
+			st.OldestWait = w
+		}
+	}
+	s.started, s.waitSum, s.waitMax = 0, 0, 0
+	s.droppedStale, s.droppedFull = 0, 0
+	return st
+}
+
+// pickLocked returns the best candidate adapterID has seen recently.
+// Candidates no adapter has seen within StaleAfter are dropped.
+func (s *ConnectScheduler) pickLocked(adapterID string, now time.Time) *connectCandidate {
+	s.pruneLocked(now)
+	anyAdapter := s.connectOnly[adapterID]
+	var best *connectCandidate
+	bestScore := 0
+	for _, c := range s.pending {
+		sg, ok := c.seen[adapterID]
+		if anyAdapter {
+			sg, ok = c.freshest()
+		}
+		if !ok || now.Sub(sg.at) > s.cfg.StaleAfter {
+			continue
+		}
+		score := connectScore(c.prio, c.watch, sg.rssi)
+		if best == nil || score > bestScore || (score == bestScore && c.queuedAt.Before(best.queuedAt)) {
+			best, bestScore = c, score
+		}
+	}
+	return best
+}
+
+func (s *ConnectScheduler) pruneLocked(now time.Time) {
+	for mac, c := range s.pending {
+		fresh := false
+		for _, sg := range c.seen {
+			if now.Sub(sg.at) <= s.cfg.StaleAfter {
+				fresh = true
+				break
+			}
+		}
+		if !fresh {
+			delete(s.pending, mac)
+			s.droppedStale++
+		}
+	}
+}
+
+// dropLowestLocked makes room for a new candidate by removing the lowest
+// priority pending one, if it ranks below the newcomer.
+func (s *ConnectScheduler) dropLowestLocked(prio ConnectPriority, watch bool, rssi int) bool {
+	newScore := connectScore(prio, watch, rssi)
+	var worst *connectCandidate
+	worstScore := 0
+	for _, c := range s.pending {
+		score := connectScore(c.prio, c.watch, c.bestRSSI())
+		if worst == nil || score < worstScore {
+			worst, worstScore = c, score
+		}
+	}
+	if worst == nil || worstScore >= newScore {
+		return false
+	}
+	delete(s.pending, worst.mac)
+	s.droppedFull++
+	return true
+}
+
+func (s *ConnectScheduler) signalLocked() {
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// freshest returns the most recent sighting by any adapter.
+func (c *connectCandidate) freshest() (connectSighting, bool) {
+	var best connectSighting
+	ok := false
+	for _, sg := range c.seen {
+		if !ok || sg.at.After(best.at) {
+			best, ok = sg, true
+		}
+	}
+	return best, ok
+}
+
+func (c *connectCandidate) bestRSSI() int {
+	best := -127
+	for _, sg := range c.seen {
+		if sg.rssi > best {
+			best = sg.rssi
+		}
+	}
+	return best
+}
+
+// connectScore ranks candidates; each class outweighs any RSSI difference.
+func connectScore(prio ConnectPriority, watch bool, rssi int) int {
+	score := rssi + 128 // 0..255 for valid RSSI values
+	if prio.Marked {
+		score += 1 << 8
+	}
+	if prio.NeverConnected {
+		score += 1 << 9
+	}
+	if watch {
+		score += 1 << 10
+	}
+	return score
+}
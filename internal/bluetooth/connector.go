@@ -73,7 +73,7 @@ func ConnectAndDumpGATT(ctx context.Context, adapter *tg.Adapter, addr tg.Addres
 	// Persist (latest + per-session history).
 	addrStr := strings.ToUpper(addr.String())
 	now := util.NowTimestamp()
-	_ = store.UpdateGattServices(ctx, addrStr, serviceList)
+	_ = store.UpdateGattServices(ctx, addrStr, serviceList, now)
 	_ = store.InsertGattServicesHistory(ctx, sessionID, addrStr, serviceList, now)
 
 	nameCopy := util.SafeName(displayName)
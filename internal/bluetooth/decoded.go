@@ -0,0 +1,101 @@
+package bluetooth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"pible/internal/db"
+	"pible/internal/decode"
+)
+
+// decodeAdvertisement runs the payload decoders over structured manufacturer
+// and service data entries. Entries that do not parse as hex are skipped.
+func decodeAdvertisement(mfg []manufacturerEntry, svc []serviceDataEntry) decode.Result {
+	frames := make([]decode.Frame, 0, 2)
+	for _, e := range mfg {
+		b := parseHexBytes(e.DataHex)
+		if len(b) == 0 {
+			continue
+		}
+		frames = append(frames, decode.Manufacturer(e.CompanyID, b)...)
+	}
+	for _, e := range svc {
+		b := parseHexBytes(e.DataHex)
+		if len(b) == 0 {
+			continue
+		}
+		frames = append(frames, decode.ServiceData(e.UUID, b)...)
+	}
+	return decode.Finish(frames)
+}
+
+// storeBeacons upserts decoded beacon frames into the beacons table.
+func storeBeacons(ctx context.Context, store *db.Store, sessionID int64, mac string, ts string, rssi *int, beacons []decode.Beacon) {
+	for _, b := range beacons {
+		_ = store.UpsertBeacon(ctx, db.BeaconParams{
+			SessionID:    &sessionID,
+			MAC:          mac,
+			Kind:         b.Kind,
+			UUID:         strPtrIfNotEmpty(b.UUID),
+			Major:        b.Major,
+			Minor:        b.Minor,
+			CompanyID:    b.CompanyID,
+			TxPower:      b.TxPower,
+			Namespace:    strPtrIfNotEmpty(b.Namespace),
+			Instance:     strPtrIfNotEmpty(b.Instance),
+			URL:          strPtrIfNotEmpty(b.URL),
+			EID:          strPtrIfNotEmpty(b.EID),
+			BatteryMV:    b.BatteryMV,
+			TemperatureC: b.TemperatureC,
+			AdvCount:     b.AdvCount,
+			UptimeS:      b.UptimeS,
+			RSSI:         rssi,
+			Timestamp:    ts,
+		})
+	}
+}
+
+// storeSensorReadings appends decoded sensor readings to the sensor_readings table.
+func storeSensorReadings(ctx context.Context, store *db.Store, sessionID int64, mac string, ts string, readings []decode.SourcedReading) {
+	rows := make([]db.SensorReadingParams, 0, len(readings))
+	for _, r := range readings {
+		rows = append(rows, db.SensorReadingParams{
+			Format: r.Format,
+			Metric: r.Metric,
+			Value:  r.Value,
+			Unit:   r.Unit,
+		})
+	}
+	_ = store.InsertSensorReadings(ctx, &sessionID, mac, ts, rows)
+}
+
+// readingsSignature is a compact fingerprint used to detect changed readings.
+func readingsSignature(readings []decode.SourcedReading) string {
+	var sb strings.Builder
+	for _, r := range readings {
+		fmt.Fprintf(&sb, "%s/%s=%g;", r.Format, r.Metric, r.Value)
+	}
+	return sb.String()
+}
+
+// storeMarkers records device type pattern matches and their captured fields.
+func storeMarkers(ctx context.Context, store *db.Store, sessionID int64, mac string, ts string, markers []MarkerMatch) {
+	for _, m := range markers {
+		var fields *string
+		if len(m.Fields) > 0 {
+			if b, err := json.Marshal(m.Fields); err == nil {
+				s := string(b)
+				fields = &s
+			}
+		}
+		_ = store.UpsertDeviceMarker(ctx, db.DeviceMarkerParams{
+			SessionID:  &sessionID,
+			MAC:        mac,
+			Pattern:    m.Name,
+			FieldsJSON: fields,
+			Timestamp:  ts,
+		})
+	}
+}
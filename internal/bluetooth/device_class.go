@@ -0,0 +1,101 @@
+package bluetooth
+
+import (
+	"fmt"
+	"strings"
+
+	"pible/internal/ids"
+)
+
+// deviceClass holds the human-readable GAP Appearance and Class of Device of a device.
+type deviceClass struct {
+	Appearance     *int
+	AppearanceName string
+	CoD            *ids.ClassOfDevice
+}
+
+func describeDeviceClass(resolver *ids.Resolver, appearance *uint16, class *uint32) deviceClass {
+	var dc deviceClass
+	if appearance != nil {
+		a := int(*appearance)
+		dc.Appearance = &a
+		dc.AppearanceName = resolver.AppearanceName(*appearance)
+	}
+	if class != nil && *class != 0 {
+		cod := ids.DecodeClassOfDevice(*class)
+		dc.CoD = &cod
+	}
+	return dc
+}
+
+// ClassOfDeviceString returns "Major / Minor" or "" when no class is known.
+func (dc deviceClass) ClassOfDeviceString() string {
+	if dc.CoD == nil {
+		return ""
+	}
+	return dc.CoD.String()
+}
+
+// classSuffix formats appearance and class for console lines.
+func (dc deviceClass) classSuffix() string {
+	var sb strings.Builder
+	if dc.AppearanceName != "" {
+		sb.WriteString(" appearance=")
+		sb.WriteString(dc.AppearanceName)
+	}
+	if s := dc.ClassOfDeviceString(); s != "" {
+		sb.WriteString(" class=")
+		sb.WriteString(s)
+	}
+	return sb.String()
+}
+
+// classicCoDParams returns the decoded CoD columns for classic_devices.
+func classicCoDParams(class *uint32) (major, minor, services *string) {
+	if class == nil || *class == 0 {
+		return nil, nil, nil
+	}
+	cod := ids.DecodeClassOfDevice(*class)
+	major = strPtrIfNotEmpty(cod.Major)
+	minor = strPtrIfNotEmpty(cod.Minor)
+	services = strPtrIfNotEmpty(strings.Join(cod.Services, ", "))
+	return major, minor, services
+}
+
+// modaliasInfo is a decoded modalias with resolved names.
+type modaliasInfo struct {
+	Vendor      string // vendor name, or hex ID when unknown
+	Product     string // product name, or hex ID when unknown
+	ProductName string // product name only (empty when unknown)
+}
+
+func describeModalias(resolver *ids.Resolver, modalias *string) (modaliasInfo, bool) {
+	if modalias == nil {
+		return modaliasInfo{}, false
+	}
+	m, ok := ids.ParseModalias(*modalias)
+	if !ok {
+		return modaliasInfo{}, false
+	}
+	info := modaliasInfo{
+		Vendor:      resolver.ModaliasVendor(m),
+		ProductName: resolver.ModaliasProduct(m),
+	}
+	if info.Vendor == "" {
+		info.Vendor = fmt.Sprintf("0x%04X", m.Vendor)
+	}
+	info.Product = info.ProductName
+	if info.Product == "" {
+		info.Product = fmt.Sprintf("0x%04X", m.Product)
+	}
+	return info, true
+}
+
+// modaliasParams returns the decoded modalias columns for classic_devices.
+func modaliasParams(resolver *ids.Resolver, modalias *string) (vendor, product *string) {
+	info, ok := describeModalias(resolver, modalias)
+	if !ok {
+		return nil, nil
+	}
+	return &info.Vendor, &info.Product
+}
@@ -0,0 +1,59 @@
+package bluetooth
+
+import "strings"
+
+// DeviceList selects devices by MAC address or name, e.g. the connection
+// watchlist or the pairing allow-list. Each line is either a MAC address
+// (exact match) or a name keyword (case-insensitive substring match). The
+// file format and reload behaviour are the same as for ConnectBlacklist.
+type DeviceList struct {
+	list *ConnectBlacklist
+}
+
+// LoadDeviceList loads a device list file.
+// If the file does not exist, (nil, nil) is returned.
+func LoadDeviceList(path string) (*DeviceList, error) {
+	l, err := LoadConnectBlacklist(path)
+	if err != nil || l == nil {
+		return nil, err
+	}
+	return &DeviceList{list: l}, nil
+}
+
+func (l *DeviceList) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.list.Path()
+}
+
+func (l *DeviceList) Entries() []string {
+	if l == nil {
+		return nil
+	}
+	return l.list.Keywords()
+}
+
+// Match returns true if the device is on the list.
+func (l *DeviceList) Match(deviceName, mac string) bool {
+	if l == nil {
+		return false
+	}
+	mac = strings.ToLower(strings.TrimSpace(mac))
+	if mac != "" {
+		for _, e := range l.list.Keywords() {
+			if e == mac {
+				return true
+			}
+		}
+	}
+	return l.list.Match(deviceName)
+}
+
+// MaybeReload reloads the file if it has changed. Best-effort; errors are ignored.
+func (l *DeviceList) MaybeReload() {
+	if l == nil {
+		return
+	}
+	l.list.MaybeReload()
+}
@@ -0,0 +1,250 @@
+package bluetooth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"pible/internal/db"
+	"pible/internal/decode"
+	"pible/internal/util"
+)
+
+// GattAttribute is one service, characteristic or descriptor of a GATT dump.
+// Value, Text and ReadError are not part of the fingerprint.
+type GattAttribute struct {
+	Kind           string   `json:"kind"` // service, characteristic, descriptor
+	Service        string   `json:"service"`
+	Characteristic string   `json:"characteristic,omitempty"`
+	UUID           string   `json:"uuid"`
+	Handle         *uint16  `json:"handle,omitempty"`
+	Flags          []string `json:"flags,omitempty"`
+	Value          string   `json:"value,omitempty"` // hex
+	Text           string   `json:"text,omitempty"`
+	ReadError      string   `json:"read_error,omitempty"`
+}
+
+// GattAttributeChange is one difference between two GATT dumps.
+type GattAttributeChange struct {
+	Op     string // "+", "-" or "~"
+	Key    string
+	Old    *GattAttribute
+	New    *GattAttribute
+	Fields []string // for "~": handle, flags and/or value
+}
+
+func newGattAttribute(kind, service, characteristic, uuid string, handle *uint16, flags []string) GattAttribute {
+	a := GattAttribute{
+		Kind:           kind,
+		Service:        strings.ToLower(service),
+		Characteristic: strings.ToLower(characteristic),
+		UUID:           strings.ToLower(uuid),
+		Handle:         handle,
+	}
+	for _, f := range flags {
+		a.Flags = append(a.Flags, strings.ToLower(f))
+	}
+	sort.Strings(a.Flags)
+	return a
+}
+
+// canonical is the fingerprint line of an attribute.
+func (a GattAttribute) canonical() string {
+	h := "-"
+	if a.Handle != nil {
+		h = fmt.Sprintf("%04x", *a.Handle)
+	}
+	return strings.Join([]string{a.Kind, a.Service, a.Characteristic, a.UUID, h, strings.Join(a.Flags, ",")}, "|")
+}
+
+// GattFingerprint hashes the UUIDs, handles and flags of all attributes.
+// The result does not depend on attribute order or values.
+func GattFingerprint(attrs []GattAttribute) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		lines = append(lines, a.canonical())
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ShortFingerprint shortens a fingerprint for console output.
+func ShortFingerprint(fp string) string {
+	if len(fp) > 16 {
+		return fp[:16]
+	}
+	return fp
+}
+
+// gattAttributeKeys indexes attributes by position in the GATT tree. Repeated
+// UUIDs at the same position are numbered in handle order so that a moved
+// handle shows up as a change, not as a removal plus an addition.
+func gattAttributeKeys(attrs []GattAttribute) map[string]GattAttribute {
+	sorted := append([]GattAttribute(nil), attrs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return handleOrder(sorted[i]) < handleOrder(sorted[j]) })
+	out := make(map[string]GattAttribute, len(sorted))
+	seen := make(map[string]int, len(sorted))
+	for _, a := range sorted {
+		base := a.Kind + " " + attributeLabel(a)
+		seen[base]++
+		k := base
+		if n := seen[base]; n > 1 {
+			k = fmt.Sprintf("%s #%d", base, n)
+		}
+		out[k] = a
+	}
+	return out
+}
+
+func handleOrder(a GattAttribute) int {
+	if a.Handle == nil {
+		return 1 << 16
+	}
+	return int(*a.Handle)
+}
+
+// attributeLabel is the service/characteristic/descriptor path of an
+// attribute, with SIG UUIDs shortened to 16 bits.
+func attributeLabel(a GattAttribute) string {
+	parts := []string{shortUUID(a.Service)}
+	switch a.Kind {
+	case "characteristic":
+		parts = append(parts, shortUUID(a.UUID))
+	case "descriptor":
+		parts = append(parts, shortUUID(a.Characteristic), shortUUID(a.UUID))
+	}
+	return strings.Join(parts, "/")
+}
+
+func shortUUID(uuid string) string {
+	if u16, ok := decode.UUID16(uuid); ok {
+		return fmt.Sprintf("%04x", u16)
+	}
+	return uuid
+}
+
+// DiffGattAttributes compares two dumps of the same device.
+func DiffGattAttributes(from, to []GattAttribute) []GattAttributeChange {
+	a := gattAttributeKeys(from)
+	b := gattAttributeKeys(to)
+	var out []GattAttributeChange
+	for k, o := range a {
+		o := o
+		n, ok := b[k]
+		if !ok {
+			out = append(out, GattAttributeChange{Op: "-", Key: k, Old: &o})
+			continue
+		}
+		var fields []string
+		if handleOrder(o) != handleOrder(n) {
+			fields = append(fields, "handle")
+		}
+		if strings.Join(o.Flags, ",") != strings.Join(n.Flags, ",") {
+			fields = append(fields, "flags")
+		}
+		if o.Value != n.Value {
+			fields = append(fields, "value")
+		}
+		if len(fields) > 0 {
+			out = append(out, GattAttributeChange{Op: "~", Key: k, Old: &o, New: &n, Fields: fields})
+		}
+	}
+	for k, n := range b {
+		n := n
+		if _, ok := a[k]; !ok {
+			out = append(out, GattAttributeChange{Op: "+", Key: k, New: &n})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// ParseGattAttributes decodes gatt_fingerprints.attributes_json.
+func ParseGattAttributes(s string) ([]GattAttribute, error) {
+	var attrs []GattAttribute
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	if err := json.Unmarshal([]byte(s), &attrs); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// gattFirmware returns the Device Information firmware and software
+// revisions found in a dump, e.g. "fw=1.2.0 sw=4.1".
+func gattFirmware(attrs []GattAttribute) string {
+	var fw, sw string
+	for _, a := range attrs {
+		if a.Kind != "characteristic" {
+			continue
+		}
+		u16, _ := decode.UUID16(a.UUID)
+		switch u16 {
+		case decode.CharFirmwareRevision:
+			fw = strings.TrimSpace(a.Text)
+		case decode.CharSoftwareRevision:
+			sw = strings.TrimSpace(a.Text)
+		}
+	}
+	var parts []string
+	if fw != "" {
+		parts = append(parts, "fw="+fw)
+	}
+	if sw != "" {
+		parts = append(parts, "sw="+sw)
+	}
+	return strings.Join(parts, " ")
+}
+
+// storeGattFingerprint records the fingerprint of a dump and flags GATT
+// layout and firmware changes against the device's previous session.
+func storeGattFingerprint(ctx context.Context, store *db.Store, sessionID int64, mac string, ts string, attrs []GattAttribute) {
+	fp := GattFingerprint(attrs)
+	if store == nil || fp == "" {
+		return
+	}
+	b, err := json.Marshal(attrs)
+	if err != nil {
+		return
+	}
+	firmware := gattFirmware(attrs)
+
+	var changes []string
+	prev, _ := store.PreviousGattFingerprint(ctx, mac, sessionID)
+	if prev != nil {
+		if prev.Fingerprint != fp {
+			changes = append(changes, "gatt")
+		}
+		prevFirmware := ""
+		if prev.Firmware != nil {
+			prevFirmware = *prev.Firmware
+		}
+		if prevFirmware != "" && firmware != "" && prevFirmware != firmware {
+			changes = append(changes, "firmware")
+			util.Linef("[FIRMWARE]", util.ColorYellow, "%s changed: %s -> %s (since session %d)", mac, prevFirmware, firmware, prev.SessionID)
+			log.Printf("firmware: %s changed: %s -> %s (since session %d)", mac, prevFirmware, firmware, prev.SessionID)
+		} else if prev.Fingerprint != fp {
+			util.Linef("[GATT]", util.ColorYellow, "%s layout changed: %s -> %s (since session %d)", mac, ShortFingerprint(prev.Fingerprint), ShortFingerprint(fp), prev.SessionID)
+		}
+	}
+
+	_ = store.UpsertGattFingerprint(ctx, db.GattFingerprint{
+		SessionID:      sessionID,
+		MAC:            mac,
+		Timestamp:      ts,
+		Fingerprint:    fp,
+		AttributesJSON: string(b),
+		Firmware:       strPtrIfNotEmpty(firmware),
+		Changes:        strPtrIfNotEmpty(strings.Join(changes, ",")),
+	})
+}
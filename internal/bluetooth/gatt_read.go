@@ -0,0 +1,198 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// GattReadOptions limits the characteristic and descriptor reads of one GATT
+// dump. Fast devices get up to MaxReads reads within Budget; slow devices raise
+// the per-read timeout up to MaxReadTimeout and run into Budget earlier, and a
+// device that stops answering is given up on after a few timeouts in a row.
+type GattReadOptions struct {
+	MaxReads       int           // reads per dump: characteristics, descriptors and long-value continuations (0 = only limited by Budget)
+	ReadTimeout    time.Duration // initial per-read timeout
+	MaxReadTimeout time.Duration // upper bound when a slow device raises the timeout
+	Budget         time.Duration // total read time per dump (0 = unlimited)
+}
+
+// DefaultGattReadOptions are tuned for "lots of devices" environments.
+func DefaultGattReadOptions() GattReadOptions {
+	return GattReadOptions{
+		MaxReads:       40,
+		ReadTimeout:    900 * time.Millisecond,
+		MaxReadTimeout: 5 * time.Second,
+		Budget:         20 * time.Second,
+	}
+}
+
+// maxAttributeLen is the largest attribute value ATT allows.
+const maxAttributeLen = 512
+
+// defaultATTMTU applies when BlueZ does not expose the negotiated MTU.
+const defaultATTMTU = 23
+
+// maxReadTimeouts is the number of timed-out reads in a row after which a
+// device is treated as unresponsive.
+const maxReadTimeouts = 3
+
+// gattReader reads characteristic and descriptor values of one device and adapts its timeout:
+// a timed-out read is retried once with twice the timeout, and the timeout
+// follows the observed latency of successful reads.
+type gattReader struct {
+	conn     *dbus.Conn
+	opts     GattReadOptions
+	timeout  time.Duration
+	started  time.Time
+	reads    int
+	timeouts int // consecutive timed-out reads
+}
+
+func newGattReader(conn *dbus.Conn, opts GattReadOptions) *gattReader {
+	def := DefaultGattReadOptions()
+	if opts.ReadTimeout <= 0 {
+		opts.ReadTimeout = def.ReadTimeout
+	}
+	if opts.MaxReadTimeout < opts.ReadTimeout {
+		opts.MaxReadTimeout = opts.ReadTimeout
+	}
+	return &gattReader{conn: conn, opts: opts, timeout: opts.ReadTimeout, started: time.Now()}
+}
+
+// limit returns why no further reads should be made, or "".
+func (r *gattReader) limit() string {
+	if r.opts.MaxReads > 0 && r.reads >= r.opts.MaxReads {
+		return "read limit reached"
+	}
+	if r.timeouts >= maxReadTimeouts {
+		return "device not answering reads"
+	}
+	if r.opts.Budget > 0 && time.Since(r.started) >= r.opts.Budget {
+		return "read time budget reached"
+	}
+	return ""
+}
+
+// readValue reads a characteristic or descriptor (iface is the D-Bus
+// interface). Values that fill a whole ATT PDU are continued with offset
+// reads, which count against MaxReads. truncated is only set when the device
+// had already returned a full chunk of continuation data and then the value
+// could not be read further, so the value is known to be incomplete.
+func (r *gattReader) readValue(ctx context.Context, path dbus.ObjectPath, iface string, mtu *uint16) (value []byte, truncated bool, err error) {
+	value, err = r.readAt(ctx, path, iface, 0)
+	if err != nil {
+		return nil, false, err
+	}
+
+	chunk := defaultATTMTU - 1
+	if mtu != nil && *mtu > 1 {
+		chunk = int(*mtu) - 1
+	}
+	// A first value of exactly one chunk may already be complete (BlueZ
+	// long-reads plain ReadValue on most versions), so only a full chunk
+	// returned by an offset read proves there is more.
+	continued := false
+	last := len(value)
+	for last >= chunk && len(value) < maxAttributeLen {
+		if r.limit() != "" {
+			return value, continued, nil
+		}
+		r.reads++
+		more, rerr := r.readAt(ctx, path, iface, uint16(len(value)))
+		if rerr != nil {
+			// Invalid offset, "attribute not long" and "request not
+			// supported" all mean the value we have is the whole value.
+			if isValueComplete(rerr) {
+				return value, false, nil
+			}
+			return value, continued, nil
+		}
+		if len(more) == 0 {
+			break
+		}
+		value = append(value, more...)
+		last = len(more)
+		continued = last >= chunk
+	}
+	return value, false, nil
+}
+
+func (r *gattReader) readAt(ctx context.Context, path dbus.ObjectPath, iface string, offset uint16) ([]byte, error) {
+	opts := map[string]dbus.Variant{}
+	if offset > 0 {
+		opts["offset"] = dbus.MakeVariant(offset)
+	}
+	v, took, err := r.call(ctx, path, iface, opts, r.timeout)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil && r.timeout < r.opts.MaxReadTimeout {
+		// Slow device: raise the timeout and retry once.
+		r.timeout = minDuration(r.timeout*2, r.opts.MaxReadTimeout)
+		v, took, err = r.call(ctx, path, iface, opts, r.timeout)
+	}
+	switch {
+	case err == nil:
+		// Keep the timeout at a few times the observed latency.
+		r.timeout = minDuration(maxDuration(r.opts.ReadTimeout, 3*took), r.opts.MaxReadTimeout)
+		r.timeouts = 0
+	case errors.Is(err, context.DeadlineExceeded):
+		r.timeouts++
+	default:
+		// The device answered (e.g. with an ATT error).
+		r.timeouts = 0
+	}
+	return v, err
+}
+
+func (r *gattReader) call(ctx context.Context, path dbus.ObjectPath, iface string, opts map[string]dbus.Variant, timeout time.Duration) ([]byte, time.Duration, error) {
+	readCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	call := r.conn.Object("org.bluez", path).CallWithContext(readCtx, iface+".ReadValue", 0, opts)
+	took := time.Since(start)
+	if call.Err != nil {
+		if readCtx.Err() != nil {
+			return nil, took, readCtx.Err()
+		}
+		return nil, took, call.Err
+	}
+	var out []byte
+	if err := call.Store(&out); err != nil {
+		return nil, took, err
+	}
+	return out, took, nil
+}
+
+// isValueComplete reports whether an offset read failed because there is
+// nothing more to read (ATT Invalid Offset, Attribute Not Long or Request Not
+// Supported).
+func isValueComplete(err error) bool {
+	es := strings.ToLower(err.Error())
+	for _, s := range []string{
+		"invalid offset", "invalidoffset",
+		"not long", "notlong",
+		"not supported", "notsupported",
+		"att error: 0x07", "att error: 0x0b", "att error: 0x06",
+	} {
+		if strings.Contains(es, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
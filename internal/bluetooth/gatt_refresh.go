@@ -0,0 +1,56 @@
+package bluetooth
+
+import (
+	"strings"
+	"time"
+
+	"pible/internal/db"
+)
+
+// GattRefreshPolicy decides when a device that already has a stored GATT
+// dump is connected again to re-read its characteristics.
+type GattRefreshPolicy struct {
+	// MaxAge re-reads a device once its last dump is older than this.
+	// Zero disables age-based refreshes.
+	MaxAge time.Duration
+	// SessionTypes lists marked device types (devices.type) that are re-read
+	// once in every session.
+	SessionTypes []string
+}
+
+// due reports whether a device should be (re)interrogated and why.
+// Devices without a GATT fingerprint are always re-read once per session.
+func (p GattRefreshPolicy) due(st db.GattReadState, now time.Time) (bool, string) {
+	if !st.HasServices {
+		return true, "new"
+	}
+	if !st.ReadInSession {
+		if !st.HasFingerprint {
+			return true, "no fingerprint"
+		}
+		for _, t := range SplitMarkedTypes(st.MarkedType) {
+			if containsFold(p.SessionTypes, t) {
+				return true, "type " + t
+			}
+		}
+	}
+	if p.MaxAge > 0 {
+		if st.LastReadAt == nil {
+			return true, "age unknown"
+		}
+		last, err := time.ParseInLocation("2006-01-02 15:04:05", *st.LastReadAt, time.Local)
+		if err != nil || now.Sub(last) >= p.MaxAge {
+			return true, "older than " + p.MaxAge.String()
+		}
+	}
+	return false, ""
+}
+
+func containsFold(list []string, v string) bool {
+	for _, s := range list {
+		if strings.EqualFold(strings.TrimSpace(s), v) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,24 +1,40 @@
 package bluetooth
 
 import (
-	"encoding/base64"
-	"encoding/binary"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // DeviceTypePatterns holds a list of tagging patterns loaded from YAML.
+// Types may be replaced at runtime by Reload/MaybeReload.
 type DeviceTypePatterns struct {
 	Types []DeviceTypePattern `yaml:"types"`
+
+	mu        sync.RWMutex
+	defPath   string
+	custPath  string
+	stamp     string
+	lastStat  time.Time
+	statEvery time.Duration
 }
 
+// DeviceTypePattern tags devices whose advertisement matches its conditions
+// (see PatternCondition). A device may match several patterns.
 type DeviceTypePattern struct {
-	Name               string `yaml:"name"`
+	Name string `yaml:"name"`
+	// Match holds the condition keys given next to name.
+	Match PatternCondition `yaml:"-"`
+
+	// Legacy Coke-ON matchers, still accepted and converted to conditions:
+	// require_service_uuid AND any of (ibeacon, manufacturer_5b, name_base64_5b).
 	RequireServiceUUID string `yaml:"require_service_uuid"`
 
 	IBeacon struct {
@@ -36,10 +52,108 @@ type DeviceTypePattern struct {
 	NameBase645B bool `yaml:"name_base64_5b"`
 }
 
+var legacyPatternKeys = []string{"require_service_uuid", "ibeacon", "manufacturer_5b", "name_base64_5b"}
+
+// UnmarshalYAML decodes and validates one device type pattern.
+func (p *DeviceTypePattern) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return patternErrorf(n, "type must be a mapping")
+	}
+	known := append(append([]string{"name"}, conditionKeys...), legacyPatternKeys...)
+	if err := checkKeys(n, known); err != nil {
+		return err
+	}
+	type plain DeviceTypePattern
+	if err := n.Decode((*plain)(p)); err != nil {
+		return err
+	}
+	// Condition keys sit next to name on the same mapping.
+	cond := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: n.Line, Column: n.Column}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		for _, k := range conditionKeys {
+			if n.Content[i].Value == k {
+				cond.Content = append(cond.Content, n.Content[i], n.Content[i+1])
+			}
+		}
+	}
+	if err := decodeCondition(cond, &p.Match); err != nil {
+		return err
+	}
+	p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+	if p.Name == "" {
+		return patternErrorf(n, "type without name")
+	}
+	if err := p.convertLegacy(n); err != nil {
+		return err
+	}
+	if p.Match.empty() {
+		return patternErrorf(n, "type %q has no conditions", p.Name)
+	}
+	return nil
+}
+
+// base64Name5Re matches a base64 encoding of exactly 5 bytes.
+const base64Name5Re = `^[A-Za-z0-9+/]{7}=$`
+
+// convertLegacy rewrites the legacy Coke-ON fields as conditions.
+func (p *DeviceTypePattern) convertLegacy(n *yaml.Node) error {
+	var alt []PatternCondition
+	if p.IBeacon.UUID != "" && p.IBeacon.AppleCompanyID > 0 {
+		uuid := strings.ReplaceAll(strings.TrimSpace(p.IBeacon.UUID), "-", "")
+		alt = append(alt, PatternCondition{Manufacturer: &ManufacturerMatch{
+			CompanyID: p.IBeacon.AppleCompanyID,
+			ByteMatch: ByteMatch{
+				Value: fmt.Sprintf("0215%s%04x%04x", uuid, p.IBeacon.Major&0xFFFF, p.IBeacon.Minor&0xFFFF),
+				Capture: map[string]Capture{
+					"ibeacon_major": {Offset: 18, Length: 2, Format: "uint_be"},
+					"ibeacon_minor": {Offset: 20, Length: 2, Format: "uint_be"},
+				},
+			},
+		}})
+	}
+	if p.Manufacturer5B.CompanyID > 0 && p.Manufacturer5B.Length > 0 {
+		alt = append(alt, PatternCondition{Manufacturer: &ManufacturerMatch{
+			CompanyID: p.Manufacturer5B.CompanyID,
+			ByteMatch: ByteMatch{
+				Length:  p.Manufacturer5B.Length,
+				Capture: map[string]Capture{"manufacturer_payload": {}},
+			},
+		}})
+	}
+	if p.NameBase645B {
+		alt = append(alt, PatternCondition{
+			NameRegex:   base64Name5Re,
+			NameCapture: map[string]Capture{"name_bytes": {Format: "base64"}},
+		})
+	}
+	for i := range alt {
+		if err := alt[i].compile(n); err != nil {
+			return err
+		}
+	}
+	if len(alt) > 0 {
+		if len(p.Match.Any) > 0 {
+			p.Match.All = append(p.Match.All, PatternCondition{Any: p.Match.Any})
+		}
+		p.Match.Any = alt
+	}
+	if p.RequireServiceUUID != "" {
+		c := PatternCondition{ServiceUUID: p.RequireServiceUUID}
+		if err := c.compile(n); err != nil {
+			return err
+		}
+		p.Match.All = append(p.Match.All, c)
+	}
+	return nil
+}
+
 // LoadDeviceTypePatterns loads patterns from:
 //   <dataDir>/default/device_types.yaml
 //   <customDir>/device_types.yaml  (optional override)
 // If customDir is empty, it defaults to <dataDir>/custom.
+// Missing files are skipped. When a file cannot be parsed the error (with line
+// and column) is returned together with the patterns of the other file; the
+// returned set is never nil, so it still reloads once the file is fixed.
 func LoadDeviceTypePatterns(dataDir, customDir string) (*DeviceTypePatterns, error) {
 	if strings.TrimSpace(dataDir) == "" {
 		dataDir = "./data"
@@ -50,19 +164,99 @@ func LoadDeviceTypePatterns(dataDir, customDir string) (*DeviceTypePatterns, err
 	}
 	customPath := filepath.Join(customDir, "device_types.yaml")
 
+	out := &DeviceTypePatterns{defPath: defPath, custPath: customPath, statEvery: 30 * time.Second}
+	// Defaults and custom overlay are both optional.
+	types, err := loadPatternTypes(defPath, customPath)
+	out.Types = types
+	out.stamp = patternsStamp(defPath, customPath)
+	out.lastStat = time.Now()
+	return out, err
+}
+
+// Reload re-reads the pattern files and swaps the new set in. When a file
+// exists but cannot be parsed the current patterns are kept and the error is
+// returned.
+func (p *DeviceTypePatterns) Reload() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.RLock()
+	defPath, custPath := p.defPath, p.custPath
+	p.mu.RUnlock()
+
+	stamp := patternsStamp(defPath, custPath)
+	types, err := loadPatternTypes(defPath, custPath)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stamp = stamp
+	if err != nil {
+		return err
+	}
+	p.Types = types
+	return nil
+}
+
+// MaybeReload reloads the pattern files if they changed. Files are checked at
+// most every statEvery. It reports whether a new set was swapped in.
+func (p *DeviceTypePatterns) MaybeReload() (bool, error) {
+	if p == nil {
+		return false, nil
+	}
+	now := time.Now()
+	p.mu.Lock()
+	if !p.lastStat.IsZero() && now.Sub(p.lastStat) < p.statEvery {
+		p.mu.Unlock()
+		return false, nil
+	}
+	p.lastStat = now
+	changed := patternsStamp(p.defPath, p.custPath) != p.stamp
+	p.mu.Unlock()
+
+	if !changed {
+		return false, nil
+	}
+	if err := p.Reload(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (p *DeviceTypePatterns) snapshot() []DeviceTypePattern {
+	if p == nil {
+		return nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.Types
+}
+
+// loadPatternTypes merges the default and custom pattern files. Missing files
+// are skipped.
+func loadPatternTypes(defPath, customPath string) ([]DeviceTypePattern, error) {
 	out := &DeviceTypePatterns{}
-	// Load defaults if present.
-	_ = loadPatternsFile(defPath, out)
-	// Overlay custom if present.
-	_ = loadPatternsFile(customPath, out)
+	var errs []error
+	for _, path := range []string{defPath, customPath} {
+		err := loadPatternsFile(path, out)
+		var pe *PatternError
+		switch {
+		case err == nil, os.IsNotExist(err):
+		case errors.As(err, &pe):
+			errs = append(errs, err)
+		default:
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	return out.Types, errors.Join(errs...)
+}
 
-	// Normalize.
-	for i := range out.Types {
-		out.Types[i].Name = strings.ToLower(strings.TrimSpace(out.Types[i].Name))
-		out.Types[i].RequireServiceUUID = strings.ToUpper(strings.TrimSpace(out.Types[i].RequireServiceUUID))
-		out.Types[i].IBeacon.UUID = strings.ToUpper(strings.TrimSpace(out.Types[i].IBeacon.UUID))
+func patternsStamp(paths ...string) string {
+	var b strings.Builder
+	for _, path := range paths {
+		if st, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d;", path, st.Size(), st.ModTime().UnixNano())
+		}
 	}
-	return out, nil
+	return b.String()
 }
 
 func loadPatternsFile(path string, out *DeviceTypePatterns) error {
@@ -72,6 +266,10 @@ func loadPatternsFile(path string, out *DeviceTypePatterns) error {
 	}
 	var tmp DeviceTypePatterns
 	if err := yaml.Unmarshal(b, &tmp); err != nil {
+		var pe *PatternError
+		if errors.As(err, &pe) {
+			pe.Path = path
+		}
 		return err
 	}
 	if len(tmp.Types) == 0 {
@@ -98,82 +296,50 @@ func loadPatternsFile(path string, out *DeviceTypePatterns) error {
 	return nil
 }
 
-var base64Re = regexp.MustCompile(`^[A-Za-z0-9+/]+={0,2}$`)
-
-// DetectTypedDevice returns a type string when any configured pattern matches.
-// Returned string is the pattern name (e.g., "cokeon").
-func DetectTypedDevice(patterns *DeviceTypePatterns, serviceUUIDsRaw []string, mfg []manufacturerEntry, name string) string {
-	if patterns == nil || len(patterns.Types) == 0 {
-		return ""
-	}
+// MarkerMatch is a matching device type pattern and the fields it captured.
+type MarkerMatch struct {
+	Name   string
+	Fields map[string]any
+}
 
-	// Build service UUID set (upper-case).
-	svcSet := map[string]struct{}{}
-	for _, u := range serviceUUIDsRaw {
-		u = strings.ToUpper(strings.TrimSpace(u))
-		if u != "" {
-			svcSet[u] = struct{}{}
-		}
+// MatchDeviceTypes returns all patterns matching the advertisement, in file
+// order, with their captured fields.
+func MatchDeviceTypes(patterns *DeviceTypePatterns, in PatternInput) []MarkerMatch {
+	types := patterns.snapshot()
+	if len(types) == 0 {
+		return nil
 	}
-
-	for _, p := range patterns.Types {
-		if p.Name == "" {
+	pin := newPatternInput(in)
+	var out []MarkerMatch
+	for i := range types {
+		if types[i].Name == "" {
 			continue
 		}
-		// Optional requirement: advertised VMINFO service UUID.
-		if p.RequireServiceUUID != "" {
-			if _, ok := svcSet[strings.ToUpper(p.RequireServiceUUID)]; !ok {
-				continue
-			}
-		}
-
-		// 1) iBeacon inside Apple manufacturer data.
-		if p.IBeacon.UUID != "" && p.IBeacon.AppleCompanyID > 0 {
-			payload := findManufacturerBytes(mfg, uint16(p.IBeacon.AppleCompanyID))
-			if len(payload) >= 23 && payload[0] == 0x02 && payload[1] == 0x15 {
-				uuid := strings.ToUpper(formatUUID(payload[2:18]))
-				major := int(binary.BigEndian.Uint16(payload[18:20]))
-				minor := int(binary.BigEndian.Uint16(payload[20:22]))
-				if uuid == strings.ToUpper(p.IBeacon.UUID) && major == p.IBeacon.Major && minor == p.IBeacon.Minor {
-					return p.Name
-				}
-			}
-		}
-
-		// 2) Manufacturer company ID with fixed length.
-		if p.Manufacturer5B.CompanyID > 0 && p.Manufacturer5B.Length > 0 {
-			payload := findManufacturerBytes(mfg, uint16(p.Manufacturer5B.CompanyID))
-			if len(payload) == p.Manufacturer5B.Length {
-				return p.Name
-			}
+		caps := map[string]any{}
+		if types[i].Match.match(pin, caps) {
+			out = append(out, MarkerMatch{Name: types[i].Name, Fields: caps})
 		}
+	}
+	return out
+}
 
-		// 3) Name base64 -> 5 bytes.
-		if p.NameBase645B {
-			n := strings.TrimSpace(name)
-			if n != "" && len(n) <= 64 && base64Re.MatchString(n) {
-				if raw, err := base64.StdEncoding.DecodeString(n); err == nil {
-					if len(raw) == 5 {
-						return p.Name
-					}
-				}
-			}
-		}
+// markerNames joins the matched pattern names as stored in devices.type.
+func markerNames(markers []MarkerMatch) string {
+	names := make([]string, 0, len(markers))
+	for _, m := range markers {
+		names = append(names, m.Name)
 	}
-	return ""
+	return strings.Join(names, ",")
 }
 
-func findManufacturerBytes(mfg []manufacturerEntry, companyID uint16) []byte {
-	for _, e := range mfg {
-		if e.CompanyID != companyID {
-			continue
-		}
-		b := parseHexBytes(e.DataHex)
-		if len(b) > 0 {
-			return b
-		}
+// DetectTypedDevices returns the names of all patterns matching the
+// advertisement, in file order (e.g. ["cokeon"]).
+func DetectTypedDevices(patterns *DeviceTypePatterns, in PatternInput) []string {
+	var out []string
+	for _, m := range MatchDeviceTypes(patterns, in) {
+		out = append(out, m.Name)
 	}
-	return nil
+	return out
 }
 
 func parseHexBytes(s string) []byte {
@@ -208,12 +374,3 @@ func parseHexBytes(s string) []byte {
 	}
 	return out
 }
-
-func formatUUID(b []byte) string {
-	if len(b) != 16 {
-		return ""
-	}
-	// 8-4-4-4-12
-	hexs := hex.EncodeToString(b)
-	return strings.ToUpper(hexs[0:8] + "-" + hexs[8:12] + "-" + hexs[12:16] + "-" + hexs[16:20] + "-" + hexs[20:32])
-}
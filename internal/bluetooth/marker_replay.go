@@ -0,0 +1,145 @@
+package bluetooth
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"pible/internal/db"
+)
+
+// PatternReplay is the result of evaluating device type patterns against the
+// stored observations of one device.
+type PatternReplay struct {
+	MAC     string
+	Name    string
+	Stored  []string // devices.type as stored
+	Matched []string // pattern names matching any stored observation
+}
+
+// ReplayPatterns evaluates patterns over devices.manufacturer_data (and the
+// other stored device columns) and every advertisements.adv_json, optionally
+// limited to one session. A device matches a pattern when any of its stored
+// observations does. No Bluetooth access is needed.
+func ReplayPatterns(ctx context.Context, store *db.Store, patterns *DeviceTypePatterns, sessionID *int64) ([]PatternReplay, error) {
+	devices, err := store.ListMarkerCandidates(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	matched := map[string]map[string]struct{}{}
+	add := func(mac string, names []string) {
+		if len(names) == 0 {
+			return
+		}
+		set := matched[mac]
+		if set == nil {
+			set = map[string]struct{}{}
+			matched[mac] = set
+		}
+		for _, n := range names {
+			set[n] = struct{}{}
+		}
+	}
+
+	for _, d := range devices {
+		add(d.MAC, DetectTypedDevices(patterns, patternInputFromDevice(d)))
+	}
+	err = store.ForEachAdvertisementJSON(ctx, sessionID, func(mac, advJSON string) error {
+		if in, ok := patternInputFromAdvJSON(advJSON); ok {
+			add(mac, DetectTypedDevices(patterns, in))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep file order for matched names.
+	order := map[string]int{}
+	for i, t := range patterns.snapshot() {
+		order[t.Name] = i
+	}
+	out := make([]PatternReplay, 0, len(devices))
+	for _, d := range devices {
+		r := PatternReplay{MAC: d.MAC, Name: d.Name, Stored: SplitMarkedTypes(d.Type)}
+		for n := range matched[d.MAC] {
+			r.Matched = append(r.Matched, n)
+		}
+		sort.Slice(r.Matched, func(i, j int) bool { return order[r.Matched[i]] < order[r.Matched[j]] })
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// SplitMarkedTypes splits a stored devices.type value into pattern names.
+func SplitMarkedTypes(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func patternInputFromDevice(d db.MarkerCandidate) PatternInput {
+	in := PatternInput{
+		Name:        d.Name,
+		AddressType: d.MACType,
+		TxPower:     atoiPtr(d.TxPower),
+		RSSI:        d.RSSI,
+	}
+	if d.ServiceUUIDs != nil {
+		var uuids []string
+		_ = json.Unmarshal([]byte(*d.ServiceUUIDs), &uuids)
+		in.ServiceUUIDs = stripUUIDAnnotations(uuids)
+	}
+	if d.ManufacturerData != nil {
+		_ = json.Unmarshal([]byte(*d.ManufacturerData), &in.Manufacturer)
+	}
+	if d.ServiceData != nil {
+		_ = json.Unmarshal([]byte(*d.ServiceData), &in.ServiceData)
+	}
+	return in
+}
+
+// patternInputFromAdvJSON reads the payload written by
+// buildAdvertisementJSON / buildAdvertisementJSONBlueZ.
+func patternInputFromAdvJSON(advJSON string) (PatternInput, bool) {
+	var p struct {
+		LocalName    string              `json:"local_name"`
+		AddressType  string              `json:"address_type"`
+		RSSI         *int                `json:"rssi"`
+		TxPower      *string             `json:"tx_power"`
+		ServiceUUIDs []string            `json:"service_uuids"`
+		Manufacturer []manufacturerEntry `json:"manufacturer"`
+		ServiceData  []serviceDataEntry  `json:"service_data"`
+	}
+	if err := json.Unmarshal([]byte(advJSON), &p); err != nil {
+		return PatternInput{}, false
+	}
+	return PatternInput{
+		ServiceUUIDs: stripUUIDAnnotations(p.ServiceUUIDs),
+		ServiceData:  p.ServiceData,
+		Manufacturer: p.Manufacturer,
+		Name:         p.LocalName,
+		AddressType:  p.AddressType,
+		TxPower:      atoiPtr(p.TxPower),
+		RSSI:         p.RSSI,
+	}, true
+}
+
+// stripUUIDAnnotations drops the " (Name)" suffix added by annotateUUIDs.
+func stripUUIDAnnotations(uuids []string) []string {
+	out := make([]string, 0, len(uuids))
+	for _, u := range uuids {
+		if i := strings.IndexByte(u, ' '); i >= 0 {
+			u = u[:i]
+		}
+		if u = strings.TrimSpace(u); u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
+}
@@ -0,0 +1,525 @@
+package bluetooth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"pible/internal/decode"
+)
+
+// PatternCondition is one node of a device type rule. Every condition that is
+// set must hold; All requires every nested condition and Any at least one.
+//
+// Example (device_types.yaml):
+//
+//	types:
+//	  - name: my_tag
+//	    service_uuid: "FEAA"
+//	    any:
+//	      - manufacturer: {company_id: 0x02BB, min_length: 5, max_length: 8}
+//	      - name_regex: '^TAG-[0-9]{4}$'
+type PatternCondition struct {
+	All []PatternCondition `yaml:"all"`
+	Any []PatternCondition `yaml:"any"`
+
+	ServiceUUID  string             `yaml:"service_uuid"`
+	ServiceData  *ServiceDataMatch  `yaml:"service_data"`
+	Manufacturer *ManufacturerMatch `yaml:"manufacturer"`
+	NameRegex    string             `yaml:"name_regex"`
+	AddressType  string             `yaml:"address_type"` // public | random
+	TxPower      *IntRange          `yaml:"tx_power"`
+	RSSI         *IntRange          `yaml:"rssi"`
+
+	// NameCapture extracts fields from the name_regex match.
+	NameCapture map[string]Capture `yaml:"name_capture"`
+
+	serviceUUID string
+	nameRe      *regexp.Regexp
+}
+
+// ByteMatch compares payload bytes at Offset with Value (hex) after applying
+// Mask (hex, same length as Value; all bits when empty). Length, MinLength and
+// MaxLength constrain the payload length.
+type ByteMatch struct {
+	Offset    int    `yaml:"offset"`
+	Mask      string `yaml:"mask"`
+	Value     string `yaml:"value"`
+	Length    int    `yaml:"length"`
+	MinLength int    `yaml:"min_length"`
+	MaxLength int    `yaml:"max_length"`
+
+	// Capture extracts fields from the matched payload.
+	Capture map[string]Capture `yaml:"capture"`
+
+	mask  []byte
+	value []byte
+}
+
+// ServiceDataMatch matches the service data of one service UUID.
+type ServiceDataMatch struct {
+	UUID      string `yaml:"uuid"`
+	ByteMatch `yaml:",inline"`
+
+	uuid string
+}
+
+// ManufacturerMatch matches the manufacturer data of one company ID.
+type ManufacturerMatch struct {
+	CompanyID int `yaml:"company_id"`
+	ByteMatch `yaml:",inline"`
+}
+
+// Capture extracts a named field when a pattern matches. From payload bytes it
+// reads Length bytes at Offset (to the end when Length is 0); from the name it
+// reads regex group Group (the whole match when empty). Format is one of hex
+// (default for bytes), text (default for names), uint_le, uint_be, int_le,
+// int_be, or base64 (name only: decoded bytes as hex).
+type Capture struct {
+	Offset int    `yaml:"offset"`
+	Length int    `yaml:"length"`
+	Group  string `yaml:"group"`
+	Format string `yaml:"format"`
+}
+
+// IntRange is an inclusive range; a missing bound is open.
+type IntRange struct {
+	Min *int `yaml:"min"`
+	Max *int `yaml:"max"`
+}
+
+// PatternError is a device type rule validation error with its position in
+// the YAML file.
+type PatternError struct {
+	Path   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *PatternError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Msg)
+}
+
+func patternErrorf(n *yaml.Node, format string, args ...any) error {
+	return &PatternError{Line: n.Line, Column: n.Column, Msg: fmt.Sprintf(format, args...)}
+}
+
+var conditionKeys = []string{"all", "any", "service_uuid", "service_data", "manufacturer", "name_regex", "name_capture", "address_type", "tx_power", "rssi"}
+
+// UnmarshalYAML decodes and validates a nested condition.
+func (c *PatternCondition) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return patternErrorf(n, "condition must be a mapping")
+	}
+	if err := checkKeys(n, conditionKeys); err != nil {
+		return err
+	}
+	if err := decodeCondition(n, c); err != nil {
+		return err
+	}
+	if c.empty() {
+		return patternErrorf(n, "empty condition")
+	}
+	return nil
+}
+
+func decodeCondition(n *yaml.Node, c *PatternCondition) error {
+	type plain PatternCondition
+	if err := n.Decode((*plain)(c)); err != nil {
+		return err
+	}
+	return c.compile(n)
+}
+
+// compile validates the condition fields set directly on n and prepares
+// them for matching. Nested All/Any conditions are compiled when decoded.
+func (c *PatternCondition) compile(n *yaml.Node) error {
+	if c.ServiceUUID != "" {
+		c.serviceUUID = decode.CanonicalUUID(c.ServiceUUID)
+		if c.serviceUUID == "" {
+			return patternErrorf(valueNode(n, "service_uuid"), "bad service_uuid %q", c.ServiceUUID)
+		}
+	}
+	if c.ServiceData != nil {
+		vn := valueNode(n, "service_data")
+		c.ServiceData.uuid = decode.CanonicalUUID(c.ServiceData.UUID)
+		if c.ServiceData.uuid == "" {
+			return patternErrorf(vn, "service_data needs a valid uuid")
+		}
+		if err := c.ServiceData.compile(vn); err != nil {
+			return err
+		}
+	}
+	if c.Manufacturer != nil {
+		vn := valueNode(n, "manufacturer")
+		if c.Manufacturer.CompanyID < 0 || c.Manufacturer.CompanyID > 0xFFFF {
+			return patternErrorf(vn, "company_id out of range: %d", c.Manufacturer.CompanyID)
+		}
+		if err := c.Manufacturer.compile(vn); err != nil {
+			return err
+		}
+	}
+	if c.NameRegex != "" {
+		re, err := regexp.Compile(c.NameRegex)
+		if err != nil {
+			return patternErrorf(valueNode(n, "name_regex"), "bad name_regex: %v", err)
+		}
+		c.nameRe = re
+	}
+	if len(c.NameCapture) > 0 {
+		vn := valueNode(n, "name_capture")
+		if c.nameRe == nil {
+			return patternErrorf(vn, "name_capture needs name_regex")
+		}
+		for field, cp := range c.NameCapture {
+			if cp.Format == "" {
+				cp.Format = "text"
+			}
+			if cp.Format != "text" && cp.Format != "base64" && cp.Format != "hex" {
+				return patternErrorf(vn, "capture %q: format must be text, base64 or hex for names", field)
+			}
+			if cp.Group != "" && c.nameRe.SubexpIndex(cp.Group) < 0 {
+				return patternErrorf(vn, "capture %q: name_regex has no group %q", field, cp.Group)
+			}
+			c.NameCapture[field] = cp
+		}
+	}
+	if c.AddressType != "" {
+		c.AddressType = strings.ToLower(strings.TrimSpace(c.AddressType))
+		if c.AddressType != "public" && c.AddressType != "random" {
+			return patternErrorf(valueNode(n, "address_type"), "address_type must be public or random, got %q", c.AddressType)
+		}
+	}
+	for _, r := range []struct {
+		key string
+		rng *IntRange
+	}{{"tx_power", c.TxPower}, {"rssi", c.RSSI}} {
+		if r.rng == nil {
+			continue
+		}
+		if r.rng.Min == nil && r.rng.Max == nil {
+			return patternErrorf(valueNode(n, r.key), "%s needs min and/or max", r.key)
+		}
+		if r.rng.Min != nil && r.rng.Max != nil && *r.rng.Min > *r.rng.Max {
+			return patternErrorf(valueNode(n, r.key), "%s min is greater than max", r.key)
+		}
+	}
+	return nil
+}
+
+func (c *PatternCondition) empty() bool {
+	return len(c.All) == 0 && len(c.Any) == 0 && c.ServiceUUID == "" && c.ServiceData == nil && c.Manufacturer == nil &&
+		c.NameRegex == "" && c.AddressType == "" && c.TxPower == nil && c.RSSI == nil
+}
+
+func (m *ByteMatch) compile(n *yaml.Node) error {
+	var err error
+	if m.value, err = parseRuleHex(m.Value); err != nil {
+		return patternErrorf(n, "bad value: %v", err)
+	}
+	if m.mask, err = parseRuleHex(m.Mask); err != nil {
+		return patternErrorf(n, "bad mask: %v", err)
+	}
+	if len(m.mask) > 0 && len(m.mask) != len(m.value) {
+		return patternErrorf(n, "mask and value must have the same length")
+	}
+	if m.Offset < 0 || m.Length < 0 || m.MinLength < 0 || m.MaxLength < 0 {
+		return patternErrorf(n, "offset and lengths must not be negative")
+	}
+	if m.MaxLength > 0 && m.MinLength > m.MaxLength {
+		return patternErrorf(n, "min_length is greater than max_length")
+	}
+	for field, cp := range m.Capture {
+		if cp.Format == "" {
+			cp.Format = "hex"
+		}
+		if cp.Offset < 0 || cp.Length < 0 {
+			return patternErrorf(n, "capture %q: offset and length must not be negative", field)
+		}
+		switch cp.Format {
+		case "hex", "text":
+		case "uint_le", "uint_be", "int_le", "int_be":
+			if cp.Length < 1 || cp.Length > 8 {
+				return patternErrorf(n, "capture %q: %s needs a length of 1 to 8 bytes", field, cp.Format)
+			}
+		default:
+			return patternErrorf(n, "capture %q: unknown format %q", field, cp.Format)
+		}
+		m.Capture[field] = cp
+	}
+	return nil
+}
+
+// parseRuleHex accepts "0215", "02 15" or "0x0215".
+func parseRuleHex(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+	s = strings.NewReplacer(" ", "", ":", "", "-", "").Replace(s)
+	if s == "" {
+		return nil, nil
+	}
+	return hex.DecodeString(s)
+}
+
+// capture extracts the configured fields from a matched payload into caps.
+func (m *ByteMatch) capture(b []byte, caps map[string]any) {
+	for field, cp := range m.Capture {
+		end := len(b)
+		if cp.Length > 0 {
+			end = cp.Offset + cp.Length
+		}
+		if cp.Offset > len(b) || end > len(b) {
+			continue
+		}
+		raw := b[cp.Offset:end]
+		switch cp.Format {
+		case "text":
+			caps[field] = strings.TrimRight(string(raw), "\x00")
+		case "uint_le", "uint_be", "int_le", "int_be":
+			var v uint64
+			for i := range raw {
+				if strings.HasSuffix(cp.Format, "_le") {
+					v |= uint64(raw[i]) << (8 * i)
+				} else {
+					v = v<<8 | uint64(raw[i])
+				}
+			}
+			if strings.HasPrefix(cp.Format, "int") {
+				shift := 64 - 8*len(raw)
+				caps[field] = int64(v<<shift) >> shift
+			} else {
+				caps[field] = v
+			}
+		default:
+			caps[field] = hex.EncodeToString(raw)
+		}
+	}
+}
+
+// captureName extracts the configured fields from the name_regex match.
+func (c *PatternCondition) captureName(name string, caps map[string]any) {
+	sub := c.nameRe.FindStringSubmatch(name)
+	if sub == nil {
+		return
+	}
+	for field, cp := range c.NameCapture {
+		v := sub[0]
+		if cp.Group != "" {
+			v = sub[c.nameRe.SubexpIndex(cp.Group)]
+		}
+		switch cp.Format {
+		case "base64":
+			raw, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				continue
+			}
+			caps[field] = hex.EncodeToString(raw)
+		case "hex":
+			caps[field] = hex.EncodeToString([]byte(v))
+		default:
+			caps[field] = v
+		}
+	}
+}
+
+func (m *ByteMatch) match(b []byte) bool {
+	if m.Length > 0 && len(b) != m.Length {
+		return false
+	}
+	if len(b) < m.MinLength || (m.MaxLength > 0 && len(b) > m.MaxLength) {
+		return false
+	}
+	if len(m.value) == 0 {
+		return true
+	}
+	if m.Offset+len(m.value) > len(b) {
+		return false
+	}
+	got := b[m.Offset : m.Offset+len(m.value)]
+	if len(m.mask) == 0 {
+		return bytes.Equal(got, m.value)
+	}
+	for i := range m.value {
+		if got[i]&m.mask[i] != m.value[i]&m.mask[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// PatternInput is the advertisement data rules are matched against.
+type PatternInput struct {
+	ServiceUUIDs []string
+	ServiceData  []serviceDataEntry
+	Manufacturer []manufacturerEntry
+	Name         string
+	AddressType  string // "random"; anything else counts as public
+	TxPower      *int
+	RSSI         *int
+}
+
+// patternInput is PatternInput with UUIDs canonicalized once per device.
+type patternInput struct {
+	PatternInput
+	services map[string]struct{}
+}
+
+func newPatternInput(in PatternInput) *patternInput {
+	p := &patternInput{PatternInput: in, services: map[string]struct{}{}}
+	if strings.ToLower(strings.TrimSpace(in.AddressType)) == "random" {
+		p.AddressType = "random"
+	} else {
+		p.AddressType = "public"
+	}
+	for _, u := range in.ServiceUUIDs {
+		if c := decode.CanonicalUUID(u); c != "" {
+			p.services[c] = struct{}{}
+		}
+	}
+	return p
+}
+
+// match reports whether the condition holds. Captured fields of the matching
+// parts are added to caps (which may be nil).
+func (c *PatternCondition) match(in *patternInput, caps map[string]any) bool {
+	local := map[string]any{}
+	if c.serviceUUID != "" {
+		if _, ok := in.services[c.serviceUUID]; !ok {
+			return false
+		}
+	}
+	if c.ServiceData != nil && !c.ServiceData.matchAny(in.ServiceData, local) {
+		return false
+	}
+	if c.Manufacturer != nil && !c.Manufacturer.matchAny(in.Manufacturer, local) {
+		return false
+	}
+	if c.nameRe != nil {
+		name := strings.TrimSpace(in.Name)
+		if !c.nameRe.MatchString(name) {
+			return false
+		}
+		if len(c.NameCapture) > 0 {
+			c.captureName(name, local)
+		}
+	}
+	if c.AddressType != "" && c.AddressType != in.AddressType {
+		return false
+	}
+	if c.TxPower != nil && !c.TxPower.contains(in.TxPower) {
+		return false
+	}
+	if c.RSSI != nil && !c.RSSI.contains(in.RSSI) {
+		return false
+	}
+	for i := range c.All {
+		if !c.All[i].match(in, local) {
+			return false
+		}
+	}
+	if len(c.Any) > 0 {
+		// Every matching alternative contributes its captures.
+		ok := false
+		for i := range c.Any {
+			if c.Any[i].match(in, local) {
+				ok = true
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	for k, v := range local {
+		if caps != nil {
+			caps[k] = v
+		}
+	}
+	return true
+}
+
+func (m *ServiceDataMatch) matchAny(entries []serviceDataEntry, caps map[string]any) bool {
+	for _, e := range entries {
+		if decode.CanonicalUUID(e.UUID) != m.uuid {
+			continue
+		}
+		if b := parseHexBytes(e.DataHex); m.match(b) {
+			m.capture(b, caps)
+			return true
+		}
+	}
+	return false
+}
+
+func (m *ManufacturerMatch) matchAny(entries []manufacturerEntry, caps map[string]any) bool {
+	for _, e := range entries {
+		if int(e.CompanyID) != m.CompanyID {
+			continue
+		}
+		if b := parseHexBytes(e.DataHex); m.match(b) {
+			m.capture(b, caps)
+			return true
+		}
+	}
+	return false
+}
+
+func (r *IntRange) contains(v *int) bool {
+	if v == nil {
+		return false
+	}
+	if r.Min != nil && *v < *r.Min {
+		return false
+	}
+	if r.Max != nil && *v > *r.Max {
+		return false
+	}
+	return true
+}
+
+// checkKeys rejects mapping keys that are not in known.
+func checkKeys(n *yaml.Node, known []string) error {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k := n.Content[i]
+		found := false
+		for _, name := range known {
+			if k.Value == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return patternErrorf(k, "unknown field %q", k.Value)
+		}
+	}
+	return nil
+}
+
+// valueNode returns the value node of key in mapping n, or n itself.
+func valueNode(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return n
+}
+
+func atoiPtr(s *string) *int {
+	if s == nil {
+		return nil
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(*s))
+	if err != nil {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,25 @@
+package bluetooth
+
+import (
+	"log"
+
+	"pible/internal/ids"
+	"pible/internal/util"
+)
+
+// maybeReloadData picks up changed identifier and pattern files. A failed
+// reload keeps the data currently in use.
+func maybeReloadData(resolver *ids.Resolver, patterns *DeviceTypePatterns) {
+	if ok, err := resolver.MaybeReload(); err != nil {
+		util.Linef("[WARN]", util.ColorYellow, "identifier data not reloaded, keeping previous: %v", err)
+		log.Printf("ids reload failed: %v", err)
+	} else if ok {
+		util.Linef("[RELOAD]", util.ColorGray, "identifier data: %s", resolver.Version())
+	}
+	if ok, err := patterns.MaybeReload(); err != nil {
+		util.Linef("[WARN]", util.ColorYellow, "device type patterns not reloaded, keeping previous: %v", err)
+		log.Printf("device type patterns reload failed: %v", err)
+	} else if ok {
+		util.Linef("[RELOAD]", util.ColorGray, "device type patterns: %d types", len(patterns.snapshot()))
+	}
+}
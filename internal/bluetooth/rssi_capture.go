@@ -0,0 +1,215 @@
+package bluetooth
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"pible/internal/db"
+	"pible/internal/util"
+)
+
+// RSSICaptureConfig selects the devices whose every RSSI update is stored.
+type RSSICaptureConfig struct {
+	Watchlist   *DeviceList // MAC addresses or name keywords
+	MarkedTypes []string    // marked device types (e.g. cokeon)
+	// Alpha is the EMA smoothing factor (0 < Alpha <= 1; higher follows
+	// changes faster).
+	Alpha float64
+	// PathLoss is the path loss exponent of the distance estimate
+	// (2 = free space, 2.5-4 indoors).
+	PathLoss float64
+}
+
+// RSSICapture records RSSI updates (PropertiesChanged) of selected devices at
+// full resolution in rssi_samples, with an EMA-smoothed RSSI and a distance
+// estimate from the advertised TxPower. Devices are selected by watchlist,
+// marked type or explicitly with Select. Watchlist and type selections follow
+// the current watchlist and markers: a device that no longer matches stops
+// being captured on its next scan snapshot. All other devices keep the
+// throttled advertisement history.
+type RSSICapture struct {
+	cfg       RSSICaptureConfig
+	store     *db.Store
+	sessionID int64
+
+	mu       sync.Mutex
+	manual   map[string]bool   // MACs selected with Select
+	selected map[string]string // MAC -> reason (watchlist, type, manual)
+	txPower  map[string]int
+	ema      map[string]float64 // MAC + "/" + adapter address -> smoothed RSSI
+	pending  []db.RSSISampleParams
+}
+
+// NewRSSICapture creates a capture. Call Run to store the samples.
+func NewRSSICapture(cfg RSSICaptureConfig, store *db.Store, sessionID int64) *RSSICapture {
+	if cfg.Alpha <= 0 || cfg.Alpha > 1 {
+		cfg.Alpha = 0.3
+	}
+	if cfg.PathLoss <= 0 {
+		cfg.PathLoss = 2.0
+	}
+	return &RSSICapture{
+		cfg:       cfg,
+		store:     store,
+		sessionID: sessionID,
+		manual:    make(map[string]bool, 8),
+		selected:  make(map[string]string, 64),
+		txPower:   make(map[string]int, 64),
+		ema:       make(map[string]float64, 64),
+		pending:   make([]db.RSSISampleParams, 0, 512),
+	}
+}
+
+// Select captures a MAC for the rest of the session, whatever the watchlist
+// and markers say.
+func (c *RSSICapture) Select(mac string) {
+	mac = strings.ToUpper(strings.TrimSpace(mac))
+	if c == nil || mac == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.manual[mac] = true
+	c.selectLocked(mac, "manual")
+}
+
+// MaybeReload reloads the watchlist if it has changed.
+func (c *RSSICapture) MaybeReload() {
+	if c == nil {
+		return
+	}
+	c.cfg.Watchlist.MaybeReload()
+}
+
+// consider re-evaluates the selection and TxPower of a device from a scan
+// snapshot. markedTypes is the comma-separated marker list.
+func (c *RSSICapture) consider(mac, name, markedTypes string, txPower *int) {
+	if c == nil {
+		return
+	}
+	reason := ""
+	if c.cfg.Watchlist.Match(name, mac) {
+		reason = "watchlist"
+	} else {
+		for _, t := range strings.Split(markedTypes, ",") {
+			if t = strings.TrimSpace(t); t != "" && containsFold(c.cfg.MarkedTypes, t) {
+				reason = "type " + t
+				break
+			}
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.manual[mac]:
+	case reason != "":
+		c.selectLocked(mac, reason)
+	default:
+		c.unselectLocked(mac)
+		return
+	}
+	if txPower != nil {
+		c.txPower[mac] = *txPower
+	}
+}
+
+func (c *RSSICapture) selectLocked(mac, reason string) {
+	_, ok := c.selected[mac]
+	c.selected[mac] = reason
+	if ok {
+		return
+	}
+	util.Linef("[RSSI]", util.ColorCyan, "capturing %s (%s)", mac, reason)
+}
+
+func (c *RSSICapture) unselectLocked(mac string) {
+	reason, ok := c.selected[mac]
+	if !ok {
+		return
+	}
+	delete(c.selected, mac)
+	delete(c.txPower, mac)
+	for key := range c.ema {
+		if strings.HasPrefix(key, mac+"/") {
+			delete(c.ema, key)
+		}
+	}
+	util.Linef("[RSSI]", util.ColorGray, "stopped capturing %s (no longer matches %s)", mac, reason)
+}
+
+// sample records an RSSI update if the device is selected.
+func (c *RSSICapture) sample(u rssiUpdate, adapterAddr, adapter string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.selected[u.mac]; !ok {
+		return
+	}
+	key := u.mac + "/" + adapterAddr
+	smoothed, ok := c.ema[key]
+	if !ok {
+		smoothed = float64(u.rssi)
+	} else {
+		smoothed += c.cfg.Alpha * (float64(u.rssi) - smoothed)
+	}
+	c.ema[key] = smoothed
+
+	row := db.RSSISampleParams{
+		MAC:          u.mac,
+		AdapterAddr:  adapterAddr,
+		Adapter:      adapter,
+		Timestamp:    u.at.Format("2006-01-02 15:04:05.000"),
+		RSSI:         u.rssi,
+		RSSISmoothed: math.Round(smoothed*10) / 10,
+	}
+	if txp, ok := c.txPower[u.mac]; ok {
+		tx := txp
+		d := estimateDistance(tx, smoothed, c.cfg.PathLoss)
+		row.TxPower = &tx
+		row.DistanceM = &d
+	}
+	c.pending = append(c.pending, row)
+}
+
+// Run stores the captured samples every second until ctx ends.
+func (c *RSSICapture) Run(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			c.flush()
+			return
+		case <-t.C:
+			c.flush()
+		}
+	}
+}
+
+func (c *RSSICapture) flush() {
+	c.mu.Lock()
+	rows := c.pending
+	c.pending = make([]db.RSSISampleParams, 0, cap(rows))
+	c.mu.Unlock()
+	if c.store == nil || len(rows) == 0 {
+		return
+	}
+	_ = c.store.InsertRSSISamples(context.Background(), c.sessionID, rows)
+}
+
+// estimateDistance is the log-distance path loss estimate in metres. The
+// reference power at 1 m is taken as TxPower - 41 dB (the usual BLE
+// approximation when no calibrated value is advertised).
+func estimateDistance(txPower int, rssi, pathLoss float64) float64 {
+	ref := float64(txPower) - 41
+	d := math.Pow(10, (ref-rssi)/(10*pathLoss))
+	return math.Round(d*100) / 100
+}
@@ -0,0 +1,146 @@
+package bluetooth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"pible/internal/db"
+)
+
+// rssiUpdate is one RSSI value BlueZ published for a device of an adapter.
+type rssiUpdate struct {
+	mac  string
+	rssi int
+	at   time.Time
+}
+
+// watchRSSI calls fn for every RSSI BlueZ publishes for the adapter's devices
+// until ctx ends: the first value when a device object appears
+// (ObjectManager InterfacesAdded) and every change after that (Device1
+// PropertiesChanged). Unlike the periodic snapshot it sees every
+// advertisement BlueZ reports a new RSSI for.
+func watchRSSI(ctx context.Context, conn *dbus.Conn, adapterID string, fn func(rssiUpdate)) error {
+	adapterPath := dbus.ObjectPath("/org/bluez/" + adapterID)
+	changedOpts := []dbus.MatchOption{
+		dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		dbus.WithMatchMember("PropertiesChanged"),
+		dbus.WithMatchPathNamespace(adapterPath),
+		dbus.WithMatchArg(0, "org.bluez.Device1"),
+	}
+	addedOpts := []dbus.MatchOption{
+		dbus.WithMatchSender("org.bluez"),
+		dbus.WithMatchInterface("org.freedesktop.DBus.ObjectManager"),
+		dbus.WithMatchMember("InterfacesAdded"),
+		dbus.WithMatchArgPath(0, string(adapterPath)+"/"),
+	}
+	if err := conn.AddMatchSignalContext(ctx, changedOpts...); err != nil {
+		return err
+	}
+	defer func() { _ = conn.RemoveMatchSignal(changedOpts...) }()
+	if err := conn.AddMatchSignalContext(ctx, addedOpts...); err != nil {
+		return err
+	}
+	defer func() { _ = conn.RemoveMatchSignal(addedOpts...) }()
+	sigCh := make(chan *dbus.Signal, 1024)
+	conn.Signal(sigCh)
+	defer conn.RemoveSignal(sigCh)
+
+	prefix := string(adapterPath) + "/dev_"
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case sig := <-sigCh:
+			if sig == nil || len(sig.Body) < 2 {
+				continue
+			}
+			var p string
+			var props map[string]dbus.Variant
+			switch sig.Name {
+			case "org.freedesktop.DBus.Properties.PropertiesChanged":
+				if iface, _ := sig.Body[0].(string); iface != "org.bluez.Device1" {
+					continue
+				}
+				p = string(sig.Path)
+				props, _ = sig.Body[1].(map[string]dbus.Variant)
+			case "org.freedesktop.DBus.ObjectManager.InterfacesAdded":
+				obj, _ := sig.Body[0].(dbus.ObjectPath)
+				ifaces, _ := sig.Body[1].(map[string]map[string]dbus.Variant)
+				p = string(obj)
+				props = ifaces["org.bluez.Device1"]
+			default:
+				continue
+			}
+			if !strings.HasPrefix(p, prefix) || strings.Contains(p[len(prefix):], "/") {
+				continue
+			}
+			v, ok := props["RSSI"]
+			if !ok {
+				continue
+			}
+			rssi, ok := v.Value().(int16)
+			if !ok {
+				continue
+			}
+			fn(rssiUpdate{
+				mac:  strings.ReplaceAll(p[len(prefix):], "_", ":"),
+				rssi: int(rssi),
+				at:   time.Now(),
+			})
+		}
+	}
+}
+
+// adapterObservations accumulates per-device RSSI statistics of one adapter
+// between flushes to device_adapter_observations.
+type adapterObservations struct {
+	mu    sync.Mutex
+	stats map[string]*db.AdapterObservationParams
+}
+
+func newAdapterObservations() *adapterObservations {
+	return &adapterObservations{stats: make(map[string]*db.AdapterObservationParams, 1024)}
+}
+
+func (o *adapterObservations) add(u rssiUpdate) {
+	ts := u.at.Format("2006-01-02 15:04:05")
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	st, ok := o.stats[u.mac]
+	if !ok {
+		o.stats[u.mac] = &db.AdapterObservationParams{
+			MAC: u.mac, RSSIMin: u.rssi, RSSIMax: u.rssi, RSSISum: u.rssi, Count: 1, FirstSeen: ts, LastSeen: ts,
+		}
+		return
+	}
+	if u.rssi < st.RSSIMin {
+		st.RSSIMin = u.rssi
+	}
+	if u.rssi > st.RSSIMax {
+		st.RSSIMax = u.rssi
+	}
+	st.RSSISum += u.rssi
+	st.Count++
+	st.LastSeen = ts
+}
+
+// flush writes and resets the accumulated statistics. It uses a background
+// context so the final flush on shutdown is not lost.
+func (o *adapterObservations) flush(store *db.Store, sessionID int64, adapterAddr, adapter string) error {
+	o.mu.Lock()
+	stats := o.stats
+	o.stats = make(map[string]*db.AdapterObservationParams, len(stats))
+	o.mu.Unlock()
+	if store == nil || len(stats) == 0 {
+		return nil
+	}
+	rows := make([]db.AdapterObservationParams, 0, len(stats))
+	for _, st := range stats {
+		rows = append(rows, *st)
+	}
+	return store.UpsertAdapterObservations(context.Background(), sessionID, adapterAddr, adapter, rows)
+}
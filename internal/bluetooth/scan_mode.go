@@ -0,0 +1,140 @@
+package bluetooth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scan modes.
+const (
+	ScanActive  = "active"  // StartDiscovery: LE scan requests (+ BR/EDR inquiry)
+	ScanPassive = "passive" // AdvertisementMonitor1 or_patterns; nothing is transmitted while scanning
+)
+
+// Discovery transports (org.bluez.Adapter1.SetDiscoveryFilter "Transport").
+const (
+	TransportAuto  = "auto"
+	TransportLE    = "le"
+	TransportBREDR = "bredr"
+)
+
+// AdapterScanConfig is how one adapter discovers devices.
+type AdapterScanConfig struct {
+	Mode      string
+	Transport string
+}
+
+func (c AdapterScanConfig) String() string {
+	return c.Mode + "/" + c.Transport
+}
+
+// ScanPlan holds the scan configuration of every adapter.
+type ScanPlan struct {
+	Default    AdapterScanConfig
+	PerAdapter map[string]AdapterScanConfig
+	// Patterns are the advertisement patterns passive adapters monitor
+	// (any match reports the device). Empty means DefaultMonitorPatterns.
+	Patterns []MonitorPattern
+	// Roles decides which adapters scan, connect or run BR/EDR inquiry.
+	Roles AdapterRoles
+}
+
+// For returns the configuration of an adapter.
+func (p ScanPlan) For(adapterID string) AdapterScanConfig {
+	if c, ok := p.PerAdapter[adapterID]; ok {
+		return c
+	}
+	return p.Default
+}
+
+// ParseScanPlan parses the -scan-mode and -transport flags. Each is either a
+// single value for all adapters or a comma-separated list of adapter=value
+// entries, optionally with a plain value as the default, e.g.
+// "passive" or "active,hci1=passive". Passive adapters always use LE; asking
+// for BR/EDR on one explicitly is an error.
+func ParseScanPlan(modeSpec, transportSpec string) (ScanPlan, error) {
+	modes, defMode, err := parseAdapterSpec(modeSpec, ScanActive, ScanActive, ScanPassive)
+	if err != nil {
+		return ScanPlan{}, fmt.Errorf("scan mode: %w", err)
+	}
+	transports, defTransport, err := parseAdapterSpec(transportSpec, "", TransportAuto, TransportLE, TransportBREDR)
+	if err != nil {
+		return ScanPlan{}, fmt.Errorf("transport: %w", err)
+	}
+
+	resolve := func(mode, transport string) (AdapterScanConfig, error) {
+		if mode == ScanPassive {
+			if transport == TransportBREDR {
+				return AdapterScanConfig{}, fmt.Errorf("passive scanning is LE only (transport %q)", transport)
+			}
+			return AdapterScanConfig{Mode: mode, Transport: TransportLE}, nil
+		}
+		if transport == "" {
+			transport = TransportAuto
+		}
+		return AdapterScanConfig{Mode: mode, Transport: transport}, nil
+	}
+
+	plan := ScanPlan{PerAdapter: map[string]AdapterScanConfig{}}
+	if plan.Default, err = resolve(defMode, defTransport); err != nil {
+		return ScanPlan{}, err
+	}
+	adapters := map[string]bool{}
+	for a := range modes {
+		adapters[a] = true
+	}
+	for a := range transports {
+		adapters[a] = true
+	}
+	for a := range adapters {
+		mode, ok := modes[a]
+		if !ok {
+			mode = defMode
+		}
+		transport, ok := transports[a]
+		if !ok && mode != ScanPassive {
+			// A default transport is meant for the active adapters.
+			transport = defTransport
+		}
+		c, err := resolve(mode, transport)
+		if err != nil {
+			return ScanPlan{}, fmt.Errorf("%s: %w", a, err)
+		}
+		plan.PerAdapter[a] = c
+	}
+	return plan, nil
+}
+
+// parseAdapterSpec parses "value" / "hci0=value,hci1=value" lists.
+func parseAdapterSpec(spec, def string, allowed ...string) (map[string]string, string, error) {
+	valid := func(v string) bool {
+		for _, a := range allowed {
+			if v == a {
+				return true
+			}
+		}
+		return false
+	}
+	out := map[string]string{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		adapter, value, found := strings.Cut(part, "=")
+		if !found {
+			value, adapter = adapter, ""
+		}
+		adapter = strings.TrimSpace(adapter)
+		value = strings.TrimSpace(value)
+		if !valid(value) {
+			return nil, "", fmt.Errorf("invalid value %q (want %s)", value, strings.Join(allowed, ", "))
+		}
+		if adapter == "" {
+			def = value
+			continue
+		}
+		out[adapter] = value
+	}
+	return out, def, nil
+}
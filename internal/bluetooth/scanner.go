@@ -33,8 +33,9 @@ type ScanDevice struct {
 }
 
 type manufacturerEntry struct {
-	CompanyID uint16 `json:"company_id"`
-	DataHex   string `json:"data_hex"`
+	CompanyID   uint16 `json:"company_id"`
+	CompanyName string `json:"company_name,omitempty"`
+	DataHex     string `json:"data_hex"`
 }
 
 type serviceDataEntry struct {
@@ -107,6 +108,7 @@ func StartContinuousScanAndConnect(
 		if blacklist != nil {
 			blacklist.MaybeReload()
 		}
+		maybeReloadData(resolver, nil)
 
 		// Drain completed connect jobs.
 		for {
@@ -342,19 +344,26 @@ func StartContinuousScanAndConnect(
 						Tag:              tag,
 					})
 
+					major, minor, services := classicCoDParams(cd.Class)
+					modVendor, modProduct := modaliasParams(resolver, cd.Modalias)
 					_ = store.UpsertClassicInfo(ctx, db.ClassicInfoParams{
-						MAC:           mac,
-						Class:         cd.Class,
-						Icon:          cd.Icon,
-						Paired:        cd.Paired,
-						Trusted:       cd.Trusted,
-						Connected:     cd.Connected,
-						Blocked:       cd.Blocked,
-						LegacyPairing: cd.LegacyPairing,
-						Modalias:      cd.Modalias,
-						UUIDsJSON:     cd.UUIDsJSON,
-						LastSeen:      &ts,
-						PropsJSON:     cd.PropsJSON,
+						MAC:             mac,
+						Class:           cd.Class,
+						Icon:            cd.Icon,
+						Paired:          cd.Paired,
+						Trusted:         cd.Trusted,
+						Connected:       cd.Connected,
+						Blocked:         cd.Blocked,
+						LegacyPairing:   cd.LegacyPairing,
+						Modalias:        cd.Modalias,
+						UUIDsJSON:       cd.UUIDsJSON,
+						LastSeen:        &ts,
+						PropsJSON:       cd.PropsJSON,
+						MajorClass:      major,
+						MinorClass:      minor,
+						ServiceClasses:  services,
+						ModaliasVendor:  modVendor,
+						ModaliasProduct: modProduct,
 					})
 				}
 			}
@@ -453,6 +462,7 @@ func scanFor(ctx context.Context, adapter *tg.Adapter, d time.Duration, resolver
 					DataHex:   util.BytesToHex(append([]byte(nil), m.Data...)),
 				})
 			}
+			mfgEntries = annotateManufacturerEntries(resolver, mfgEntries)
 
 			// Service data.
 			svcEntries := make([]serviceDataEntry, 0, len(svcData))
@@ -476,13 +486,14 @@ func scanFor(ctx context.Context, adapter *tg.Adapter, d time.Duration, resolver
 
 			rssi := int(res.RSSI)
 
-			// Vendor from OUI (may be empty for private/random addresses).
+			// Vendor from IEEE registries (empty for private/random addresses).
 			var vendor *string
 			if resolver != nil {
-				if v := strings.TrimSpace(resolver.VendorForMAC(mac)); v != "" {
+				if v := strings.TrimSpace(resolver.VendorForAddress(mac, res.Address.IsRandom())); v != "" {
 					vendor = &v
 				}
 			}
+			vendor = manufacturerNameFallback(vendor, res.Address.IsRandom(), mfgEntries)
 
 			advRaw, advJSON, txPowerStr, platformDataStr := buildAdvertisementJSON(localName, serviceUUIDStrs, mfgEntries, svcEntries, advBytes)
 
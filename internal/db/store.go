@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -78,7 +79,19 @@ CREATE TABLE IF NOT EXISTS devices (
 	detection_count INTEGER DEFAULT 1,
 	last_count_update TEXT,
 	tag TEXT,
-	type TEXT
+	type TEXT,
+	product TEXT,
+	appearance INTEGER,
+	appearance_name TEXT,
+	class_of_device TEXT,
+	dis_manufacturer TEXT,
+	dis_model TEXT,
+	dis_serial TEXT,
+	dis_firmware TEXT,
+	dis_hardware TEXT,
+	dis_software TEXT,
+	dis_pnp_id TEXT,
+	dis_system_id TEXT
 );
 `)
 	if err != nil {
@@ -97,6 +110,14 @@ CREATE TABLE IF NOT EXISTS devices (
 	_ = execIgnore(s.db, ctx, `ALTER TABLE devices ADD COLUMN last_count_update TEXT`)
 	_ = execIgnore(s.db, ctx, `ALTER TABLE devices ADD COLUMN tag TEXT`)
 	_ = execIgnore(s.db, ctx, `ALTER TABLE devices ADD COLUMN type TEXT`)
+	_ = execIgnore(s.db, ctx, `ALTER TABLE devices ADD COLUMN product TEXT`)
+	_ = execIgnore(s.db, ctx, `ALTER TABLE devices ADD COLUMN appearance INTEGER`)
+	_ = execIgnore(s.db, ctx, `ALTER TABLE devices ADD COLUMN appearance_name TEXT`)
+	_ = execIgnore(s.db, ctx, `ALTER TABLE devices ADD COLUMN class_of_device TEXT`)
+	// Device Information Service values read over GATT.
+	for _, col := range []string{"dis_manufacturer", "dis_model", "dis_serial", "dis_firmware", "dis_hardware", "dis_software", "dis_pnp_id", "dis_system_id"} {
+		_ = execIgnore(s.db, ctx, `ALTER TABLE devices ADD COLUMN `+col+` TEXT`)
+	}
 
 	// Migration for older schemas (DROP COLUMN is not guaranteed to be supported).
 	if err := s.migrateDevicesTableIfNeeded(ctx); err != nil {
@@ -117,12 +138,22 @@ CREATE TABLE IF NOT EXISTS classic_devices (
 	modalias TEXT,
 	uuids TEXT,
 	last_seen TEXT,
-	props_json TEXT
+	props_json TEXT,
+	major_class TEXT,
+	minor_class TEXT,
+	service_classes TEXT,
+	modalias_vendor TEXT,
+	modalias_product TEXT
 );
 `)
 	if err != nil {
 		return err
 	}
+	_ = execIgnore(s.db, ctx, `ALTER TABLE classic_devices ADD COLUMN major_class TEXT`)
+	_ = execIgnore(s.db, ctx, `ALTER TABLE classic_devices ADD COLUMN minor_class TEXT`)
+	_ = execIgnore(s.db, ctx, `ALTER TABLE classic_devices ADD COLUMN service_classes TEXT`)
+	_ = execIgnore(s.db, ctx, `ALTER TABLE classic_devices ADD COLUMN modalias_vendor TEXT`)
+	_ = execIgnore(s.db, ctx, `ALTER TABLE classic_devices ADD COLUMN modalias_product TEXT`)
 
 	_, err = s.db.ExecContext(ctx, `
 CREATE TABLE IF NOT EXISTS classic_discoveries (
@@ -142,12 +173,14 @@ CREATE TABLE IF NOT EXISTS classic_discoveries (
 	_, err = s.db.ExecContext(ctx, `
 CREATE TABLE IF NOT EXISTS gatt_services (
 	mac TEXT PRIMARY KEY,
-	service TEXT
+	service TEXT,
+	last_read_at TEXT
 );
 `)
 	if err != nil {
 		return err
 	}
+	_ = execIgnore(s.db, ctx, `ALTER TABLE gatt_services ADD COLUMN last_read_at TEXT`)
 
 	_, err = s.db.ExecContext(ctx, `
 CREATE TABLE IF NOT EXISTS gatt_characteristics (
@@ -159,16 +192,40 @@ CREATE TABLE IF NOT EXISTS gatt_characteristics (
 	flags_json TEXT,
 	value_hex TEXT,
 	value_ascii TEXT,
+	value_decoded TEXT,
 	read_error TEXT,
 	last_read_at TEXT,
+	mtu INTEGER,
+	value_truncated INTEGER,
 	PRIMARY KEY (mac, service_uuid, char_uuid)
 );
 `)
 	if err != nil {
 		return err
 	}
+	_ = execIgnore(s.db, ctx, `ALTER TABLE gatt_characteristics ADD COLUMN value_decoded TEXT`)
+	_ = execIgnore(s.db, ctx, `ALTER TABLE gatt_characteristics ADD COLUMN mtu INTEGER`)
+	_ = execIgnore(s.db, ctx, `ALTER TABLE gatt_characteristics ADD COLUMN value_truncated INTEGER`)
 	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_gatt_chars_mac ON gatt_characteristics(mac)`)
 
+	// Characteristic values over time; a row is added when a read value differs from the stored one.
+	_, err = s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS gatt_value_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id INTEGER,
+	mac TEXT,
+	service_uuid TEXT,
+	char_uuid TEXT,
+	timestamp TEXT,
+	value_hex TEXT,
+	value_decoded TEXT
+);
+`)
+	if err != nil {
+		return err
+	}
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_gatt_value_history_mac_char ON gatt_value_history(mac, char_uuid, timestamp)`)
+
 	_, err = s.db.ExecContext(ctx, `
 CREATE TABLE IF NOT EXISTS gatt_descriptors (
 	mac TEXT,
@@ -189,18 +246,140 @@ CREATE TABLE IF NOT EXISTS gatt_descriptors (
 	}
 	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_gatt_desc_mac ON gatt_descriptors(mac)`)
 
+	// One row per connection attempt (BlueZ connect workers).
+	_, err = s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS connection_attempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id INTEGER,
+	mac TEXT,
+	adapter TEXT,
+	started_at TEXT,
+	ended_at TEXT,
+	duration_ms INTEGER,
+	outcome TEXT,
+	error_class TEXT,
+	error TEXT
+);
+`)
+	if err != nil {
+		return err
+	}
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_connection_attempts_mac ON connection_attempts(mac, started_at)`)
+
+	// Opt-in pairing attempts (Just Works agent).
+	_, err = s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS pairing_attempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id INTEGER,
+	mac TEXT,
+	adapter TEXT,
+	timestamp TEXT,
+	outcome TEXT,
+	error_class TEXT,
+	error TEXT,
+	protected_before INTEGER,
+	protected_after INTEGER,
+	bond_removed INTEGER
+);
+`)
+	if err != nil {
+		return err
+	}
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_pairing_attempts_mac ON pairing_attempts(mac)`)
+
+	// RSSI per device and adapter (by controller address) per session.
+	_, err = s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS device_adapter_observations (
+	session_id INTEGER,
+	mac TEXT,
+	adapter_addr TEXT,
+	adapter TEXT,
+	rssi_min INTEGER,
+	rssi_max INTEGER,
+	rssi_avg REAL,
+	rssi_sum INTEGER,
+	count INTEGER,
+	first_seen TEXT,
+	last_seen TEXT,
+	PRIMARY KEY (session_id, mac, adapter_addr)
+);
+`)
+	if err != nil {
+		return err
+	}
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_device_adapter_observations_mac ON device_adapter_observations(mac)`)
+
+	// Every RSSI update of devices selected for high-resolution capture (append-only).
+	_, err = s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS rssi_samples (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id INTEGER,
+	mac TEXT,
+	adapter_addr TEXT,
+	adapter TEXT,
+	timestamp TEXT,
+	rssi INTEGER,
+	rssi_smoothed REAL,
+	tx_power INTEGER,
+	distance_m REAL
+);
+`)
+	if err != nil {
+		return err
+	}
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_rssi_samples_mac_ts ON rssi_samples(mac, timestamp)`)
+
+	// Canonical GATT layout hash per device and session.
+	_, err = s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS gatt_fingerprints (
+	session_id INTEGER,
+	mac TEXT,
+	timestamp TEXT,
+	fingerprint TEXT,
+	attributes_json TEXT,
+	firmware TEXT,
+	changes TEXT,
+	PRIMARY KEY (session_id, mac)
+);
+`)
+	if err != nil {
+		return err
+	}
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_gatt_fingerprints_fp ON gatt_fingerprints(fingerprint)`)
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_gatt_fingerprints_mac ON gatt_fingerprints(mac)`)
+
+	// Values received through notify/indicate subscriptions.
+	_, err = s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS gatt_notifications (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id INTEGER,
+	mac TEXT,
+	service_uuid TEXT,
+	char_uuid TEXT,
+	timestamp TEXT,
+	value_hex TEXT,
+	value_decoded TEXT
+);
+`)
+	if err != nil {
+		return err
+	}
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_gatt_notifications_mac_char_ts ON gatt_notifications(mac, char_uuid, timestamp)`)
+
 	_, err = s.db.ExecContext(ctx, `
 CREATE TABLE IF NOT EXISTS scan_sessions (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
 	started_at TEXT,
 	adapter TEXT,
 	tag TEXT,
-	gps_start TEXT
+	gps_start TEXT,
+	scan_modes TEXT
 );
 `)
 	if err != nil {
 		return err
 	}
+	_ = execIgnore(s.db, ctx, `ALTER TABLE scan_sessions ADD COLUMN scan_modes TEXT`)
 
 	_, err = s.db.ExecContext(ctx, `
 CREATE TABLE IF NOT EXISTS advertisements (
@@ -237,6 +416,73 @@ CREATE TABLE IF NOT EXISTS gatt_services_history (
 	if err != nil {
 		return err
 	}
+
+	// Decoded beacon frames (latest per device and beacon kind).
+	_, err = s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS beacons (
+	mac TEXT,
+	kind TEXT,
+	session_id INTEGER,
+	uuid TEXT,
+	major INTEGER,
+	minor INTEGER,
+	company_id INTEGER,
+	tx_power INTEGER,
+	namespace TEXT,
+	instance TEXT,
+	url TEXT,
+	eid TEXT,
+	battery_mv INTEGER,
+	temperature_c REAL,
+	adv_count INTEGER,
+	uptime_s REAL,
+	rssi INTEGER,
+	first_seen TEXT,
+	last_seen TEXT,
+	PRIMARY KEY (mac, kind)
+);
+`)
+	if err != nil {
+		return err
+	}
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_beacons_uuid ON beacons(uuid, major, minor)`)
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_beacons_namespace ON beacons(namespace, instance)`)
+
+	// Decoded sensor readings (time series).
+	_, err = s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS sensor_readings (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id INTEGER,
+	mac TEXT,
+	timestamp TEXT,
+	format TEXT,
+	metric TEXT,
+	value REAL,
+	unit TEXT
+);
+`)
+	if err != nil {
+		return err
+	}
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_sensor_readings_mac_metric_ts ON sensor_readings(mac, metric, timestamp)`)
+
+	// Device type pattern matches with their captured fields.
+	_, err = s.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS device_markers (
+	mac TEXT,
+	pattern TEXT,
+	session_id INTEGER,
+	fields_json TEXT,
+	first_seen TEXT,
+	last_seen TEXT,
+	PRIMARY KEY (mac, pattern)
+);
+`)
+	if err != nil {
+		return err
+	}
+	_ = execIgnore(s.db, ctx, `CREATE INDEX IF NOT EXISTS idx_device_markers_pattern ON device_markers(pattern)`)
+
 	// GPS history
 	return s.initGPSHistory(ctx)
 }
@@ -317,7 +563,19 @@ CREATE TABLE IF NOT EXISTS devices_new (
 	detection_count INTEGER DEFAULT 1,
 	last_count_update TEXT,
 	tag TEXT,
-	type TEXT
+	type TEXT,
+	product TEXT,
+	appearance INTEGER,
+	appearance_name TEXT,
+	class_of_device TEXT,
+	dis_manufacturer TEXT,
+	dis_model TEXT,
+	dis_serial TEXT,
+	dis_firmware TEXT,
+	dis_hardware TEXT,
+	dis_software TEXT,
+	dis_pnp_id TEXT,
+	dis_system_id TEXT
 );
 `)
 	if err != nil {
@@ -524,6 +782,10 @@ type SaveParams struct {
 	TxPower           *string
 	PlatformData      *string
 	AdvertisementJSON *string
+	Product           *string
+	Appearance        *int
+	AppearanceName    *string
+	ClassOfDevice     *string
 	LastAdvID         *int64
 	GPS               *string
 	ServiceList       *string
@@ -661,6 +923,22 @@ func (s *Store) SaveDevice(ctx context.Context, p SaveParams) error {
 				fields = append(fields, "advertisement_json = ?")
 				args = append(args, *p.AdvertisementJSON)
 			}
+			if p.Product != nil {
+				fields = append(fields, "product = ?")
+				args = append(args, *p.Product)
+			}
+			if p.Appearance != nil {
+				fields = append(fields, "appearance = ?")
+				args = append(args, *p.Appearance)
+			}
+			if p.AppearanceName != nil {
+				fields = append(fields, "appearance_name = ?")
+				args = append(args, *p.AppearanceName)
+			}
+			if p.ClassOfDevice != nil {
+				fields = append(fields, "class_of_device = ?")
+				args = append(args, *p.ClassOfDevice)
+			}
 			if p.LastAdvID != nil {
 				fields = append(fields, "last_adv_id = ?")
 				args = append(args, *p.LastAdvID)
@@ -706,8 +984,9 @@ INSERT OR IGNORE INTO devices (
 	manufacturer_name, service_uuids, service_data, tx_power, platform_data, gps,
 	advertisement_json,
 	last_adv_id,
-	service, detection_count, last_count_update, tag, type
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	service, detection_count, last_count_update, tag, type, product,
+	appearance, appearance_name, class_of_device
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `,
 		optInt64(p.SessionID),
 		optString(p.DeviceType),
@@ -732,6 +1011,10 @@ INSERT OR IGNORE INTO devices (
 		optString(p.Timestamp),
 		optString(p.Tag),
 		optString(p.MarkedType),
+		optString(p.Product),
+		optInt(p.Appearance),
+		optString(p.AppearanceName),
+		optString(p.ClassOfDevice),
 	)
 	return err
 }
@@ -889,7 +1172,7 @@ func optUint16(p *uint16) any {
 	return int64(*p)
 }
 
-func (s *Store) UpdateGattServices(ctx context.Context, mac string, services string) error {
+func (s *Store) UpdateGattServices(ctx context.Context, mac string, services string, ts string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	mac = normalizeMAC(mac)
@@ -898,22 +1181,70 @@ func (s *Store) UpdateGattServices(ctx context.Context, mac string, services str
 	}
 
 	_, err := s.db.ExecContext(ctx, `
-INSERT INTO gatt_services (mac, service)
-VALUES (?, ?)
-ON CONFLICT(mac) DO UPDATE SET service = excluded.service
-`, mac, services)
+INSERT INTO gatt_services (mac, service, last_read_at)
+VALUES (?, ?, ?)
+ON CONFLICT(mac) DO UPDATE SET service = excluded.service, last_read_at = excluded.last_read_at
+`, mac, services, ts)
 	return err
 }
 
+// GattReadState describes what is stored about a device's GATT database.
+type GattReadState struct {
+	HasServices    bool
+	LastReadAt     *string // gatt_services.last_read_at, or the newest characteristic read for older rows
+	HasFingerprint bool
+	ReadInSession  bool // a dump exists for the given session
+	MarkedType     string
+}
+
+// GattReadState returns the stored GATT read state of a device.
+func (s *Store) GattReadState(ctx context.Context, mac string, sessionID int64) (GattReadState, error) {
+	var st GattReadState
+	mac = normalizeMAC(mac)
+	if mac == "" {
+		return st, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var (
+		hasServices, hasFP, inSession int
+		lastRead, markedType          sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, `
+SELECT
+	EXISTS(SELECT 1 FROM gatt_services WHERE mac = ? AND service IS NOT NULL AND service != ''),
+	COALESCE(
+		(SELECT last_read_at FROM gatt_services WHERE mac = ?),
+		(SELECT MAX(last_read_at) FROM gatt_characteristics WHERE mac = ?)
+	),
+	EXISTS(SELECT 1 FROM gatt_fingerprints WHERE mac = ?),
+	EXISTS(SELECT 1 FROM gatt_services_history WHERE mac = ? AND session_id = ?),
+	(SELECT type FROM devices WHERE mac = ?)
+`, mac, mac, mac, mac, mac, sessionID, mac).Scan(&hasServices, &lastRead, &hasFP, &inSession, &markedType)
+	if err != nil {
+		return st, err
+	}
+	st.HasServices = hasServices != 0
+	st.LastReadAt = nullStringPtr(lastRead)
+	st.HasFingerprint = hasFP != 0
+	st.ReadInSession = inSession != 0
+	st.MarkedType = strings.TrimSpace(markedType.String)
+	return st, nil
+}
+
 type GattCharacteristicParams struct {
+	SessionID     *int64
 	MAC           string
 	ServiceUUID   string
 	ServiceHandle *uint16
 	CharUUID      string
 	CharHandle    *uint16
 	FlagsJSON     *string
+	MTU           *uint16 // ATT MTU BlueZ reported for the characteristic
 	ValueHex      *string
 	ValueASCII    *string
+	ValueDecoded  *string // JSON object from a standard characteristic decoder
+	Truncated     bool    // ValueHex may be incomplete (a long read stopped early)
 	ReadError     *string
 	LastReadAt    string
 }
@@ -925,16 +1256,39 @@ func (s *Store) UpsertGattCharacteristic(ctx context.Context, p GattCharacterist
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+
+	// Append to the value history when the read value differs from the stored one.
+	if p.ValueHex != nil {
+		var prev sql.NullString
+		err := s.db.QueryRowContext(ctx, `SELECT value_hex FROM gatt_characteristics WHERE mac = ? AND service_uuid = ? AND char_uuid = ?`,
+			p.MAC, strings.TrimSpace(p.ServiceUUID), strings.TrimSpace(p.CharUUID)).Scan(&prev)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if !prev.Valid || prev.String != *p.ValueHex {
+			_, err = s.db.ExecContext(ctx, `
+INSERT INTO gatt_value_history (session_id, mac, service_uuid, char_uuid, timestamp, value_hex, value_decoded)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`, optInt64(p.SessionID), p.MAC, strings.TrimSpace(p.ServiceUUID), strings.TrimSpace(p.CharUUID), p.LastReadAt, *p.ValueHex, optString(p.ValueDecoded))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	_, err := s.db.ExecContext(ctx, `
 INSERT INTO gatt_characteristics (
-	mac, service_uuid, service_handle, char_uuid, char_handle, flags_json, value_hex, value_ascii, read_error, last_read_at
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	mac, service_uuid, service_handle, char_uuid, char_handle, flags_json, mtu, value_hex, value_ascii, value_decoded, value_truncated, read_error, last_read_at
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT(mac, service_uuid, char_uuid) DO UPDATE SET
 	service_handle = COALESCE(excluded.service_handle, gatt_characteristics.service_handle),
 	char_handle = COALESCE(excluded.char_handle, gatt_characteristics.char_handle),
 	flags_json = COALESCE(excluded.flags_json, gatt_characteristics.flags_json),
+	mtu = COALESCE(excluded.mtu, gatt_characteristics.mtu),
+	value_truncated = CASE WHEN excluded.value_hex IS NULL THEN gatt_characteristics.value_truncated ELSE excluded.value_truncated END,
 	value_hex = COALESCE(excluded.value_hex, gatt_characteristics.value_hex),
 	value_ascii = COALESCE(excluded.value_ascii, gatt_characteristics.value_ascii),
+	value_decoded = COALESCE(excluded.value_decoded, gatt_characteristics.value_decoded),
 	read_error = excluded.read_error,
 	last_read_at = excluded.last_read_at
 `,
@@ -944,14 +1298,69 @@ ON CONFLICT(mac, service_uuid, char_uuid) DO UPDATE SET
 		strings.TrimSpace(p.CharUUID),
 		optUint16(p.CharHandle),
 		optString(p.FlagsJSON),
+		optUint16(p.MTU),
 		optString(p.ValueHex),
 		optString(p.ValueASCII),
+		optString(p.ValueDecoded),
+		boolToInt(p.Truncated),
 		optString(p.ReadError),
 		p.LastReadAt,
 	)
 	return err
 }
 
+// DeviceInfoParams holds Device Information Service values read over GATT.
+type DeviceInfoParams struct {
+	Manufacturer     *string
+	Model            *string
+	Serial           *string
+	FirmwareRevision *string
+	HardwareRevision *string
+	SoftwareRevision *string
+	PnPID            *string
+	SystemID         *string
+}
+
+// Empty reports whether no value is set.
+func (p DeviceInfoParams) Empty() bool {
+	return p.Manufacturer == nil && p.Model == nil && p.Serial == nil && p.FirmwareRevision == nil &&
+		p.HardwareRevision == nil && p.SoftwareRevision == nil && p.PnPID == nil && p.SystemID == nil
+}
+
+// UpdateDeviceInfo stores Device Information Service values on devices.
+// Values that were not read keep their previous content.
+func (s *Store) UpdateDeviceInfo(ctx context.Context, mac string, p DeviceInfoParams) error {
+	mac = normalizeMAC(mac)
+	if mac == "" || p.Empty() {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.ExecContext(ctx, `
+UPDATE devices SET
+	dis_manufacturer = COALESCE(?, dis_manufacturer),
+	dis_model = COALESCE(?, dis_model),
+	dis_serial = COALESCE(?, dis_serial),
+	dis_firmware = COALESCE(?, dis_firmware),
+	dis_hardware = COALESCE(?, dis_hardware),
+	dis_software = COALESCE(?, dis_software),
+	dis_pnp_id = COALESCE(?, dis_pnp_id),
+	dis_system_id = COALESCE(?, dis_system_id)
+WHERE mac = ?
+`,
+		optString(p.Manufacturer),
+		optString(p.Model),
+		optString(p.Serial),
+		optString(p.FirmwareRevision),
+		optString(p.HardwareRevision),
+		optString(p.SoftwareRevision),
+		optString(p.PnPID),
+		optString(p.SystemID),
+		mac,
+	)
+	return err
+}
+
 type GattDescriptorParams struct {
 	MAC         string
 	ServiceUUID string
@@ -1042,6 +1451,42 @@ func (s *Store) CreateSession(ctx context.Context, adapter string, tag *string,
 	return id, nil
 }
 
+// SetSessionScanMode records how an adapter scans in a session. scan_modes
+// holds one "adapter=mode/transport" entry per adapter, e.g.
+// "hci0=active/auto,hci1=passive/le"; a restarted adapter replaces its entry.
+func (s *Store) SetSessionScanMode(ctx context.Context, sessionID int64, adapter, mode string) error {
+	adapter = strings.TrimSpace(adapter)
+	if adapter == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cur sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT scan_modes FROM scan_sessions WHERE id = ?`, sessionID).Scan(&cur)
+	if err != nil {
+		return err
+	}
+	modes := map[string]string{}
+	for _, part := range strings.Split(cur.String, ",") {
+		if a, m, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			modes[a] = m
+		}
+	}
+	modes[adapter] = mode
+	keys := make([]string, 0, len(modes))
+	for a := range modes {
+		keys = append(keys, a)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, a := range keys {
+		parts = append(parts, a+"="+modes[a])
+	}
+	_, err = s.db.ExecContext(ctx, `UPDATE scan_sessions SET scan_modes = ? WHERE id = ?`, strings.Join(parts, ","), sessionID)
+	return err
+}
+
 type AdvertisementParams struct {
 	SessionID *int64
 	MAC       string
@@ -1105,6 +1550,15 @@ type ClassicInfoParams struct {
 	UUIDsJSON     *string
 	LastSeen      *string
 	PropsJSON     *string
+
+	// Decoded Class of Device.
+	MajorClass     *string
+	MinorClass     *string
+	ServiceClasses *string
+
+	// Decoded modalias (vendor/product names or hex IDs).
+	ModaliasVendor  *string
+	ModaliasProduct *string
 }
 
 func (s *Store) UpsertClassicInfo(ctx context.Context, p ClassicInfoParams) error {
@@ -1116,8 +1570,9 @@ func (s *Store) UpsertClassicInfo(ctx context.Context, p ClassicInfoParams) erro
 	defer s.mu.Unlock()
 	_, err := s.db.ExecContext(ctx, `
 INSERT INTO classic_devices (
-	mac, class, icon, paired, trusted, connected, blocked, legacy_pairing, modalias, uuids, last_seen, props_json
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	mac, class, icon, paired, trusted, connected, blocked, legacy_pairing, modalias, uuids, last_seen, props_json,
+	major_class, minor_class, service_classes, modalias_vendor, modalias_product
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT(mac) DO UPDATE SET
 	class = COALESCE(excluded.class, classic_devices.class),
 	icon = COALESCE(excluded.icon, classic_devices.icon),
@@ -1129,7 +1584,12 @@ ON CONFLICT(mac) DO UPDATE SET
 	modalias = COALESCE(excluded.modalias, classic_devices.modalias),
 	uuids = COALESCE(excluded.uuids, classic_devices.uuids),
 	last_seen = COALESCE(excluded.last_seen, classic_devices.last_seen),
-	props_json = COALESCE(excluded.props_json, classic_devices.props_json)
+	props_json = COALESCE(excluded.props_json, classic_devices.props_json),
+	major_class = COALESCE(excluded.major_class, classic_devices.major_class),
+	minor_class = COALESCE(excluded.minor_class, classic_devices.minor_class),
+	service_classes = COALESCE(excluded.service_classes, classic_devices.service_classes),
+	modalias_vendor = COALESCE(excluded.modalias_vendor, classic_devices.modalias_vendor),
+	modalias_product = COALESCE(excluded.modalias_product, classic_devices.modalias_product)
 `,
 		p.MAC,
 		optUint32(p.Class),
@@ -1143,6 +1603,11 @@ ON CONFLICT(mac) DO UPDATE SET
 		optString(p.UUIDsJSON),
 		optString(p.LastSeen),
 		optString(p.PropsJSON),
+		optString(p.MajorClass),
+		optString(p.MinorClass),
+		optString(p.ServiceClasses),
+		optString(p.ModaliasVendor),
+		optString(p.ModaliasProduct),
 	)
 	return err
 }
@@ -1202,3 +1667,841 @@ ON CONFLICT(session_id, mac) DO UPDATE SET timestamp = excluded.timestamp, servi
 `, sessionID, mac, ts, services)
 	return err
 }
+
+type BeaconParams struct {
+	SessionID    *int64
+	MAC          string
+	Kind         string
+	UUID         *string
+	Major        *int
+	Minor        *int
+	CompanyID    *int
+	TxPower      *int
+	Namespace    *string
+	Instance     *string
+	URL          *string
+	EID          *string
+	BatteryMV    *int
+	TemperatureC *float64
+	AdvCount     *int64
+	UptimeS      *float64
+	RSSI         *int
+	Timestamp    string
+}
+
+// UpsertBeacon records the latest decoded frame of a beacon kind for a device.
+// first_seen is kept from the first insert; other fields are refreshed when present.
+func (s *Store) UpsertBeacon(ctx context.Context, p BeaconParams) error {
+	p.MAC = normalizeMAC(p.MAC)
+	p.Kind = strings.TrimSpace(p.Kind)
+	if p.MAC == "" || p.Kind == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO beacons (
+	mac, kind, session_id, uuid, major, minor, company_id, tx_power, namespace, instance, url, eid,
+	battery_mv, temperature_c, adv_count, uptime_s, rssi, first_seen, last_seen
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(mac, kind) DO UPDATE SET
+	session_id = COALESCE(excluded.session_id, beacons.session_id),
+	uuid = COALESCE(excluded.uuid, beacons.uuid),
+	major = COALESCE(excluded.major, beacons.major),
+	minor = COALESCE(excluded.minor, beacons.minor),
+	company_id = COALESCE(excluded.company_id, beacons.company_id),
+	tx_power = COALESCE(excluded.tx_power, beacons.tx_power),
+	namespace = COALESCE(excluded.namespace, beacons.namespace),
+	instance = COALESCE(excluded.instance, beacons.instance),
+	url = COALESCE(excluded.url, beacons.url),
+	eid = COALESCE(excluded.eid, beacons.eid),
+	battery_mv = COALESCE(excluded.battery_mv, beacons.battery_mv),
+	temperature_c = COALESCE(excluded.temperature_c, beacons.temperature_c),
+	adv_count = COALESCE(excluded.adv_count, beacons.adv_count),
+	uptime_s = COALESCE(excluded.uptime_s, beacons.uptime_s),
+	rssi = COALESCE(excluded.rssi, beacons.rssi),
+	last_seen = excluded.last_seen
+`,
+		p.MAC,
+		p.Kind,
+		optInt64(p.SessionID),
+		optString(p.UUID),
+		optInt(p.Major),
+		optInt(p.Minor),
+		optInt(p.CompanyID),
+		optInt(p.TxPower),
+		optString(p.Namespace),
+		optString(p.Instance),
+		optString(p.URL),
+		optString(p.EID),
+		optInt(p.BatteryMV),
+		optFloat64(p.TemperatureC),
+		optInt64(p.AdvCount),
+		optFloat64(p.UptimeS),
+		optInt(p.RSSI),
+		p.Timestamp,
+		p.Timestamp,
+	)
+	return err
+}
+
+func optFloat64(p *float64) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+type SensorReadingParams struct {
+	Format string
+	Metric string
+	Value  float64
+	Unit   string
+}
+
+// InsertSensorReadings appends one decoded advertisement's readings in a single transaction.
+func (s *Store) InsertSensorReadings(ctx context.Context, sessionID *int64, mac string, ts string, readings []SensorReadingParams) error {
+	mac = normalizeMAC(mac)
+	if mac == "" || len(readings) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO sensor_readings (session_id, mac, timestamp, format, metric, value, unit)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range readings {
+		if _, err := stmt.ExecContext(ctx, optInt64(sessionID), mac, ts, r.Format, r.Metric, r.Value, strPtrOrNil(r.Unit)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func strPtrOrNil(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+type GattNotificationParams struct {
+	ServiceUUID  string
+	CharUUID     string
+	Timestamp    string
+	ValueHex     string
+	ValueDecoded *string
+}
+
+// InsertGattNotifications appends notify/indicate values received from a device.
+func (s *Store) InsertGattNotifications(ctx context.Context, sessionID *int64, mac string, rows []GattNotificationParams) error {
+	mac = normalizeMAC(mac)
+	if mac == "" || len(rows) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO gatt_notifications (session_id, mac, service_uuid, char_uuid, timestamp, value_hex, value_decoded)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range rows {
+		if _, err := stmt.ExecContext(ctx, optInt64(sessionID), mac, strPtrOrNil(r.ServiceUUID), r.CharUUID, r.Timestamp, r.ValueHex, optString(r.ValueDecoded)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+type ConnectionAttemptParams struct {
+	SessionID  *int64
+	MAC        string
+	Adapter    string
+	StartedAt  string
+	EndedAt    string
+	DurationMS int64
+	Outcome    string // ok, error
+	ErrorClass *string
+	Error      *string
+}
+
+func (s *Store) InsertConnectionAttempt(ctx context.Context, p ConnectionAttemptParams) error {
+	p.MAC = normalizeMAC(p.MAC)
+	if p.MAC == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO connection_attempts (session_id, mac, adapter, started_at, ended_at, duration_ms, outcome, error_class, error)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, optInt64(p.SessionID), p.MAC, strPtrOrNil(p.Adapter), p.StartedAt, p.EndedAt, p.DurationMS, p.Outcome, optString(p.ErrorClass), optString(p.Error))
+	return err
+}
+
+type PairingAttemptParams struct {
+	SessionID       *int64
+	MAC             string
+	Adapter         string
+	Timestamp       string
+	Outcome         string // paired, already_paired, failed
+	ErrorClass      *string
+	Error           *string
+	ProtectedBefore int  // characteristics failing with an authentication error before pairing
+	ProtectedAfter  *int // the same after re-reading; nil when not re-read
+	BondRemoved     bool
+}
+
+func (s *Store) InsertPairingAttempt(ctx context.Context, p PairingAttemptParams) error {
+	p.MAC = normalizeMAC(p.MAC)
+	if p.MAC == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO pairing_attempts (session_id, mac, adapter, timestamp, outcome, error_class, error, protected_before, protected_after, bond_removed)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, optInt64(p.SessionID), p.MAC, strPtrOrNil(p.Adapter), p.Timestamp, p.Outcome, optString(p.ErrorClass), optString(p.Error),
+		p.ProtectedBefore, optInt(p.ProtectedAfter), boolToInt(p.BondRemoved))
+	return err
+}
+
+// ConnectBackoff is the persisted retry state of a device: failed attempts
+// since its last successful connection and the time of its last attempt.
+type ConnectBackoff struct {
+	MAC         string
+	Failures    int
+	LastAttempt string
+}
+
+// ListConnectBackoff returns the devices whose latest attempts failed, with
+// the number of failures since their last success. Only failures with one of
+// the given error classes are counted.
+func (s *Store) ListConnectBackoff(ctx context.Context, classes []string) ([]ConnectBackoff, error) {
+	if len(classes) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(classes))
+	for i, c := range classes {
+		args[i] = c
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.db.QueryContext(ctx, `
+SELECT a.mac, COUNT(*), MAX(a.started_at)
+FROM connection_attempts a
+WHERE a.outcome != 'ok'
+	AND a.error_class IN (?`+strings.Repeat(", ?", len(classes)-1)+`)
+	AND a.id > COALESCE((SELECT MAX(b.id) FROM connection_attempts b WHERE b.mac = a.mac AND b.outcome = 'ok'), 0)
+GROUP BY a.mac
+`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ConnectBackoff
+	for rows.Next() {
+		var b ConnectBackoff
+		var last sql.NullString
+		if err := rows.Scan(&b.MAC, &b.Failures, &last); err != nil {
+			return nil, err
+		}
+		b.MAC = normalizeMAC(b.MAC)
+		b.LastAttempt = last.String
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// DeviceConnectStats summarises the connection attempts of one device.
+type DeviceConnectStats struct {
+	MAC         string
+	Name        string
+	Attempts    int
+	Successes   int
+	Failures    int
+	ByClass     map[string]int // failures per error class
+	LastAttempt string
+	LastSuccess string
+	LastError   string
+}
+
+// ListDeviceConnectStats aggregates connection_attempts per device, optionally
+// only attempts started at or after since ("" = all). Canceled attempts are skipped.
+func (s *Store) ListDeviceConnectStats(ctx context.Context, since string) ([]DeviceConnectStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.db.QueryContext(ctx, `
+SELECT a.mac, COALESCE(d.name, ''), a.outcome, COALESCE(a.error_class, ''), COALESCE(a.error, ''), a.started_at
+FROM connection_attempts a
+LEFT JOIN devices d ON d.mac = a.mac
+WHERE a.started_at >= ? AND COALESCE(a.error_class, '') != 'canceled'
+ORDER BY a.mac, a.id
+`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DeviceConnectStats
+	for rows.Next() {
+		var mac, name, outcome, class, errText, started string
+		if err := rows.Scan(&mac, &name, &outcome, &class, &errText, &started); err != nil {
+			return nil, err
+		}
+		mac = normalizeMAC(mac)
+		if len(out) == 0 || out[len(out)-1].MAC != mac {
+			out = append(out, DeviceConnectStats{MAC: mac, Name: name, ByClass: map[string]int{}})
+		}
+		st := &out[len(out)-1]
+		st.Attempts++
+		st.LastAttempt = started
+		if outcome == "ok" {
+			st.Successes++
+			st.LastSuccess = started
+			continue
+		}
+		st.Failures++
+		st.ByClass[class]++
+		st.LastError = errText
+	}
+	return out, rows.Err()
+}
+
+// GattFingerprint is one row of gatt_fingerprints.
+type GattFingerprint struct {
+	SessionID      int64
+	MAC            string
+	Timestamp      string
+	Fingerprint    string
+	AttributesJSON string
+	Firmware       *string
+	Changes        *string // comma-separated: gatt, firmware
+}
+
+// UpsertGattFingerprint stores the fingerprint of a device for a session.
+func (s *Store) UpsertGattFingerprint(ctx context.Context, p GattFingerprint) error {
+	p.MAC = normalizeMAC(p.MAC)
+	if p.MAC == "" || p.Fingerprint == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO gatt_fingerprints (session_id, mac, timestamp, fingerprint, attributes_json, firmware, changes)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(session_id, mac) DO UPDATE SET
+	timestamp = excluded.timestamp,
+	fingerprint = excluded.fingerprint,
+	attributes_json = excluded.attributes_json,
+	firmware = excluded.firmware,
+	changes = excluded.changes
+`, p.SessionID, p.MAC, p.Timestamp, p.Fingerprint, p.AttributesJSON, optString(p.Firmware), optString(p.Changes))
+	return err
+}
+
+// PreviousGattFingerprint returns the latest fingerprint of a device from a
+// session other than sessionID, or nil when there is none.
+func (s *Store) PreviousGattFingerprint(ctx context.Context, mac string, sessionID int64) (*GattFingerprint, error) {
+	mac = normalizeMAC(mac)
+	if mac == "" {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.db.QueryContext(ctx, `
+SELECT session_id, mac, timestamp, fingerprint, attributes_json, firmware, changes
+FROM gatt_fingerprints WHERE mac = ? AND session_id != ?
+ORDER BY timestamp DESC, session_id DESC LIMIT 1
+`, mac, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	out, err := scanGattFingerprints(rows)
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+	return &out[0], nil
+}
+
+// ListGattFingerprints returns all fingerprints of a device, oldest session first.
+func (s *Store) ListGattFingerprints(ctx context.Context, mac string) ([]GattFingerprint, error) {
+	mac = normalizeMAC(mac)
+	if mac == "" {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.db.QueryContext(ctx, `
+SELECT session_id, mac, timestamp, fingerprint, attributes_json, firmware, changes
+FROM gatt_fingerprints WHERE mac = ?
+ORDER BY session_id, timestamp
+`, mac)
+	if err != nil {
+		return nil, err
+	}
+	return scanGattFingerprints(rows)
+}
+
+func scanGattFingerprints(rows *sql.Rows) ([]GattFingerprint, error) {
+	defer rows.Close()
+	var out []GattFingerprint
+	for rows.Next() {
+		var (
+			f                 GattFingerprint
+			ts, fp, attrs     sql.NullString
+			firmware, changes sql.NullString
+		)
+		if err := rows.Scan(&f.SessionID, &f.MAC, &ts, &fp, &attrs, &firmware, &changes); err != nil {
+			return nil, err
+		}
+		f.Timestamp = ts.String
+		f.Fingerprint = fp.String
+		f.AttributesJSON = attrs.String
+		f.Firmware = nullStringPtr(firmware)
+		f.Changes = nullStringPtr(changes)
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+// FingerprintDevice is a device that reported a given GATT fingerprint.
+type FingerprintDevice struct {
+	MAC          string
+	Name         string
+	Manufacturer string
+	Model        string
+	LastSeen     string
+}
+
+// SharedFingerprint groups the devices that reported the same fingerprint.
+type SharedFingerprint struct {
+	Fingerprint string
+	Devices     []FingerprintDevice
+}
+
+// ListSharedGattFingerprints returns fingerprints reported by at least
+// minDevices distinct devices, most common first.
+func (s *Store) ListSharedGattFingerprints(ctx context.Context, minDevices int) ([]SharedFingerprint, error) {
+	if minDevices < 1 {
+		minDevices = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.db.QueryContext(ctx, `
+SELECT f.fingerprint, f.mac, d.name, d.dis_manufacturer, d.dis_model, MAX(f.timestamp)
+FROM gatt_fingerprints f
+LEFT JOIN devices d ON d.mac = f.mac
+WHERE f.fingerprint IN (
+	SELECT fingerprint FROM gatt_fingerprints GROUP BY fingerprint HAVING COUNT(DISTINCT mac) >= ?
+)
+GROUP BY f.fingerprint, f.mac
+ORDER BY f.fingerprint, f.mac
+`, minDevices)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SharedFingerprint
+	for rows.Next() {
+		var (
+			fp, mac                string
+			name, manuf, model, ts sql.NullString
+		)
+		if err := rows.Scan(&fp, &mac, &name, &manuf, &model, &ts); err != nil {
+			return nil, err
+		}
+		if len(out) == 0 || out[len(out)-1].Fingerprint != fp {
+			out = append(out, SharedFingerprint{Fingerprint: fp})
+		}
+		g := &out[len(out)-1]
+		g.Devices = append(g.Devices, FingerprintDevice{
+			MAC:          normalizeMAC(mac),
+			Name:         name.String,
+			Manufacturer: manuf.String,
+			Model:        model.String,
+			LastSeen:     ts.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool { return len(out[i].Devices) > len(out[j].Devices) })
+	return out, nil
+}
+
+type DeviceMarkerParams struct {
+	SessionID  *int64
+	MAC        string
+	Pattern    string
+	FieldsJSON *string
+	Timestamp  string
+}
+
+// UpsertDeviceMarker records a device type pattern match for a device.
+// first_seen is kept from the first insert; captured fields are refreshed when present.
+func (s *Store) UpsertDeviceMarker(ctx context.Context, p DeviceMarkerParams) error {
+	p.MAC = normalizeMAC(p.MAC)
+	p.Pattern = strings.TrimSpace(p.Pattern)
+	if p.MAC == "" || p.Pattern == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO device_markers (mac, pattern, session_id, fields_json, first_seen, last_seen)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(mac, pattern) DO UPDATE SET
+	session_id = COALESCE(excluded.session_id, device_markers.session_id),
+	fields_json = COALESCE(excluded.fields_json, device_markers.fields_json),
+	last_seen = excluded.last_seen
+`,
+		p.MAC,
+		p.Pattern,
+		optInt64(p.SessionID),
+		optString(p.FieldsJSON),
+		p.Timestamp,
+		p.Timestamp,
+	)
+	return err
+}
+
+// MarkerCandidate is a stored device with the fields device type patterns
+// are matched against.
+type MarkerCandidate struct {
+	MAC              string
+	Name             string
+	MACType          string
+	Type             string // current devices.type (comma-separated pattern names)
+	RSSI             *int
+	TxPower          *string
+	ManufacturerData *string // JSON array
+	ServiceUUIDs     *string // JSON array
+	ServiceData      *string // JSON array
+}
+
+// ListMarkerCandidates returns all devices, or only the devices seen in the
+// given session.
+func (s *Store) ListMarkerCandidates(ctx context.Context, sessionID *int64) ([]MarkerCandidate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := `SELECT mac, name, mac_type, type, rssi, tx_power, manufacturer_data, service_uuids, service_data FROM devices`
+	var args []any
+	if sessionID != nil {
+		q += ` WHERE session_id = ? OR mac IN (SELECT mac FROM advertisements WHERE session_id = ?)`
+		args = append(args, *sessionID, *sessionID)
+	}
+	q += ` ORDER BY mac`
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []MarkerCandidate
+	for rows.Next() {
+		var (
+			c                      MarkerCandidate
+			name, macType, typ     sql.NullString
+			rssi                   sql.NullInt64
+			txPower, mfg, svcUUIDs sql.NullString
+			svcData                sql.NullString
+		)
+		if err := rows.Scan(&c.MAC, &name, &macType, &typ, &rssi, &txPower, &mfg, &svcUUIDs, &svcData); err != nil {
+			return nil, err
+		}
+		c.MAC = normalizeMAC(c.MAC)
+		c.Name = name.String
+		c.MACType = macType.String
+		c.Type = strings.TrimSpace(typ.String)
+		if rssi.Valid {
+			v := int(rssi.Int64)
+			c.RSSI = &v
+		}
+		c.TxPower = nullStringPtr(txPower)
+		c.ManufacturerData = nullStringPtr(mfg)
+		c.ServiceUUIDs = nullStringPtr(svcUUIDs)
+		c.ServiceData = nullStringPtr(svcData)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// ForEachAdvertisementJSON calls fn for every stored advertisement with a
+// JSON payload, optionally limited to one session. fn must not call back into
+// the Store.
+func (s *Store) ForEachAdvertisementJSON(ctx context.Context, sessionID *int64, fn func(mac, advJSON string) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := `SELECT mac, adv_json FROM advertisements WHERE adv_json IS NOT NULL AND adv_json != ''`
+	var args []any
+	if sessionID != nil {
+		q += ` AND session_id = ?`
+		args = append(args, *sessionID)
+	}
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var mac, advJSON string
+		if err := rows.Scan(&mac, &advJSON); err != nil {
+			return err
+		}
+		if err := fn(normalizeMAC(mac), advJSON); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// SetDeviceMarkedType replaces devices.type; an empty value clears it.
+// Unlike UpdateDeviceMarkedType it is meant for offline re-evaluation.
+func (s *Store) SetDeviceMarkedType(ctx context.Context, mac string, markedType string) error {
+	mac = normalizeMAC(mac)
+	if mac == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.ExecContext(ctx, `UPDATE devices SET type = ? WHERE mac = ?`, strPtrOrNil(markedType), mac)
+	return err
+}
+
+// AdapterObservationParams is the RSSI summary of one device as seen by one
+// adapter over a flush period.
+type AdapterObservationParams struct {
+	MAC       string
+	RSSIMin   int
+	RSSIMax   int
+	RSSISum   int
+	Count     int
+	FirstSeen string
+	LastSeen  string
+}
+
+// UpsertAdapterObservations merges RSSI summaries of one adapter into
+// device_adapter_observations. The row per (session, device, adapter address)
+// keeps the overall min/max/average, the sample count and the first and last
+// time seen.
+func (s *Store) UpsertAdapterObservations(ctx context.Context, sessionID int64, adapterAddr, adapter string, rows []AdapterObservationParams) error {
+	adapterAddr = strings.ToUpper(strings.TrimSpace(adapterAddr))
+	if adapterAddr == "" || len(rows) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO device_adapter_observations (
+	session_id, mac, adapter_addr, adapter, rssi_min, rssi_max, rssi_avg, rssi_sum, count, first_seen, last_seen
+) VALUES (?, ?, ?, ?, ?, ?, CAST(? AS REAL) / ?, ?, ?, ?, ?)
+ON CONFLICT(session_id, mac, adapter_addr) DO UPDATE SET
+	adapter = excluded.adapter,
+	rssi_min = MIN(device_adapter_observations.rssi_min, excluded.rssi_min),
+	rssi_max = MAX(device_adapter_observations.rssi_max, excluded.rssi_max),
+	rssi_sum = device_adapter_observations.rssi_sum + excluded.rssi_sum,
+	count = device_adapter_observations.count + excluded.count,
+	rssi_avg = CAST(device_adapter_observations.rssi_sum + excluded.rssi_sum AS REAL) / (device_adapter_observations.count + excluded.count),
+	first_seen = MIN(device_adapter_observations.first_seen, excluded.first_seen),
+	last_seen = MAX(device_adapter_observations.last_seen, excluded.last_seen)
+`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range rows {
+		mac := normalizeMAC(r.MAC)
+		if mac == "" || r.Count < 1 {
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx, sessionID, mac, adapterAddr, strPtrOrNil(adapter), r.RSSIMin, r.RSSIMax,
+			r.RSSISum, r.Count, r.RSSISum, r.Count, r.FirstSeen, r.LastSeen); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// AdapterObservation is a device's RSSI summary for one adapter.
+type AdapterObservation struct {
+	AdapterAddr string
+	Adapter     string
+	Sessions    int
+	RSSIMin     int
+	RSSIMax     int
+	RSSIAvg     float64
+	Count       int
+	FirstSeen   string
+	LastSeen    string
+}
+
+// ListAdapterObservations returns a device's per-adapter RSSI summary,
+// strongest average first. sessionID 0 combines all sessions.
+func (s *Store) ListAdapterObservations(ctx context.Context, mac string, sessionID int64) ([]AdapterObservation, error) {
+	mac = normalizeMAC(mac)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.db.QueryContext(ctx, `
+SELECT adapter_addr, COALESCE(MAX(adapter), ''), COUNT(DISTINCT session_id), MIN(rssi_min), MAX(rssi_max),
+	CAST(SUM(rssi_sum) AS REAL) / SUM(count), SUM(count), MIN(first_seen), MAX(last_seen)
+FROM device_adapter_observations
+WHERE mac = ? AND (? = 0 OR session_id = ?) AND count > 0
+GROUP BY adapter_addr
+ORDER BY 6 DESC
+`, mac, sessionID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []AdapterObservation
+	for rows.Next() {
+		var o AdapterObservation
+		if err := rows.Scan(&o.AdapterAddr, &o.Adapter, &o.Sessions, &o.RSSIMin, &o.RSSIMax, &o.RSSIAvg, &o.Count, &o.FirstSeen, &o.LastSeen); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// RSSISampleParams is one captured RSSI update.
+type RSSISampleParams struct {
+	MAC          string
+	AdapterAddr  string
+	Adapter      string
+	Timestamp    string // millisecond resolution
+	RSSI         int
+	RSSISmoothed float64
+	TxPower      *int
+	DistanceM    *float64 // estimate from TxPower; nil without TxPower
+}
+
+// InsertRSSISamples appends captured RSSI updates to rssi_samples.
+func (s *Store) InsertRSSISamples(ctx context.Context, sessionID int64, rows []RSSISampleParams) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO rssi_samples (session_id, mac, adapter_addr, adapter, timestamp, rssi, rssi_smoothed, tx_power, distance_m)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range rows {
+		mac := normalizeMAC(r.MAC)
+		if mac == "" {
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx, sessionID, mac, strPtrOrNil(r.AdapterAddr), strPtrOrNil(r.Adapter), r.Timestamp,
+			r.RSSI, r.RSSISmoothed, optInt(r.TxPower), optFloat64(r.DistanceM)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RSSISample is a stored RSSI update.
+type RSSISample struct {
+	SessionID    int64
+	AdapterAddr  string
+	Timestamp    string
+	RSSI         int
+	RSSISmoothed float64
+	TxPower      *int
+	DistanceM    *float64
+}
+
+// ListRSSISamples returns the newest captured samples of a device in time
+// order. sessionID 0 means all sessions; limit 0 means no limit.
+func (s *Store) ListRSSISamples(ctx context.Context, mac string, sessionID int64, limit int) ([]RSSISample, error) {
+	mac = normalizeMAC(mac)
+	if limit <= 0 {
+		limit = -1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.db.QueryContext(ctx, `
+SELECT session_id, COALESCE(adapter_addr, ''), timestamp, rssi, rssi_smoothed, tx_power, distance_m
+FROM (
+	SELECT * FROM rssi_samples
+	WHERE mac = ? AND (? = 0 OR session_id = ?)
+	ORDER BY id DESC
+	LIMIT ?
+)
+ORDER BY id
+`, mac, sessionID, sessionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []RSSISample
+	for rows.Next() {
+		var r RSSISample
+		var txp sql.NullInt64
+		var dist sql.NullFloat64
+		if err := rows.Scan(&r.SessionID, &r.AdapterAddr, &r.Timestamp, &r.RSSI, &r.RSSISmoothed, &txp, &dist); err != nil {
+			return nil, err
+		}
+		if txp.Valid {
+			v := int(txp.Int64)
+			r.TxPower = &v
+		}
+		if dist.Valid {
+			v := dist.Float64
+			r.DistanceM = &v
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func nullStringPtr(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	s := v.String
+	return &s
+}
@@ -0,0 +1,381 @@
+package decode
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// CompanyApple is the Bluetooth SIG company identifier of Apple, Inc.
+const CompanyApple uint16 = 0x004C
+
+// AppleMessage is one Continuity TLV from an Apple manufacturer payload.
+type AppleMessage struct {
+	TypeHex string         `json:"type"`
+	Name    string         `json:"name,omitempty"`
+	DataHex string         `json:"data_hex"`
+	Fields  map[string]any `json:"fields,omitempty"`
+
+	product string
+	rank    int
+}
+
+// Continuity message types.
+const (
+	appleIBeacon          = 0x02
+	appleAirPrint         = 0x03
+	appleAirDrop          = 0x05
+	appleHomeKit          = 0x06
+	appleProximityPairing = 0x07
+	appleHeySiri          = 0x08
+	appleAirPlayTarget    = 0x09
+	appleAirPlaySource    = 0x0A
+	appleMagicSwitch      = 0x0B
+	appleHandoff          = 0x0C
+	appleTetheringTarget  = 0x0D
+	appleTetheringSource  = 0x0E
+	appleNearbyAction     = 0x0F
+	appleNearbyInfo       = 0x10
+	appleFindMy           = 0x12
+)
+
+// Product ranks: higher is more specific.
+const (
+	rankGeneric = 1
+	rankFamily  = 2
+	rankModel   = 3
+)
+
+var appleTypeNames = map[byte]string{
+	appleIBeacon:          "iBeacon",
+	appleAirPrint:         "AirPrint",
+	appleAirDrop:          "AirDrop",
+	appleHomeKit:          "HomeKit",
+	appleProximityPairing: "Proximity Pairing",
+	appleHeySiri:          "Hey Siri",
+	appleAirPlayTarget:    "AirPlay Target",
+	appleAirPlaySource:    "AirPlay Source",
+	appleMagicSwitch:      "Magic Switch",
+	appleHandoff:          "Handoff",
+	appleTetheringTarget:  "Tethering Target Presence",
+	appleTetheringSource:  "Tethering Source Presence",
+	appleNearbyAction:     "Nearby Action",
+	appleNearbyInfo:       "Nearby Info",
+	appleFindMy:           "Find My",
+}
+
+// Proximity pairing device models (big-endian model field).
+var appleAudioModels = map[uint16]string{
+	0x0220: "AirPods",
+	0x0F20: "AirPods (2nd generation)",
+	0x1320: "AirPods (3rd generation)",
+	0x1920: "AirPods 4",
+	0x1B20: "AirPods 4 (ANC)",
+	0x0E20: "AirPods Pro",
+	0x1420: "AirPods Pro (2nd generation)",
+	0x2420: "AirPods Pro (2nd generation, USB-C)",
+	0x0A20: "AirPods Max",
+	0x1F20: "AirPods Max (USB-C)",
+	0x0320: "Powerbeats3",
+	0x0B20: "Powerbeats Pro",
+	0x0C20: "Beats Solo Pro",
+	0x0520: "BeatsX",
+	0x0620: "Beats Solo3",
+	0x0920: "Beats Studio3",
+	0x1020: "Beats Flex",
+	0x1120: "Beats Studio Buds",
+	0x1220: "Beats Fit Pro",
+	0x1620: "Beats Studio Buds+",
+	0x1720: "Beats Studio Pro",
+	0x1D20: "Powerbeats Pro 2",
+}
+
+// Hey Siri device class.
+var appleSiriDeviceClass = map[uint16]string{
+	0x0002: "iPhone",
+	0x0003: "iPad",
+	0x0007: "HomePod",
+	0x0009: "MacBook",
+	0x000A: "Apple Watch",
+}
+
+var appleNearbyActionCodes = map[byte]string{
+	0x00: "activity level unknown",
+	0x01: "activity reporting disabled",
+	0x03: "idle user",
+	0x05: "audio playing, screen locked",
+	0x07: "active user, screen on",
+	0x09: "screen on, video playing",
+	0x0A: "watch on wrist and unlocked",
+	0x0B: "recent user interaction",
+	0x0D: "user is driving",
+	0x0E: "phone or FaceTime call",
+}
+
+var appleNearbyActionTypes = map[byte]string{
+	0x01: "Apple TV setup",
+	0x04: "mobile backup",
+	0x05: "watch setup",
+	0x06: "Apple TV pair",
+	0x07: "internet relay",
+	0x08: "Wi-Fi password",
+	0x09: "iOS setup",
+	0x0A: "repair",
+	0x0B: "speaker setup",
+	0x0C: "Apple Pay",
+	0x0D: "whole home audio setup",
+	0x0E: "developer tools pairing request",
+	0x0F: "answered call",
+	0x10: "ended call",
+	0x11: "DD ping",
+	0x12: "DD pong",
+	0x13: "remote auto fill",
+	0x14: "companion link proximity",
+	0x15: "remote management",
+	0x16: "remote auto fill pong",
+	0x17: "remote display",
+}
+
+// Find My status byte bits 4-5.
+var appleFindMyDeviceTypes = map[byte]string{
+	0: "Apple device",
+	1: "AirTag",
+	2: "Find My accessory",
+	3: "AirPods",
+}
+
+var appleFindMyBattery = map[byte]string{
+	0: "full",
+	1: "medium",
+	2: "low",
+	3: "critical",
+}
+
+// DecodeAppleContinuity splits an Apple manufacturer payload into its
+// Continuity TLVs and decodes the known message types.
+// Messages with a truncated length are returned with only their raw bytes.
+func DecodeAppleContinuity(data []byte) []AppleMessage {
+	out := make([]AppleMessage, 0, 2)
+	for i := 0; i+1 < len(data); {
+		typ := data[i]
+		l := int(data[i+1])
+		start := i + 2
+		end := start + l
+		truncated := false
+		if end > len(data) {
+			end = len(data)
+			truncated = true
+		}
+		body := data[start:end]
+
+		m := AppleMessage{
+			TypeHex: fmt.Sprintf("0x%02X", typ),
+			Name:    appleTypeNames[typ],
+			DataHex: hexString(body),
+		}
+		if truncated {
+			m.Fields = map[string]any{"truncated": true}
+		} else {
+			decodeAppleMessage(typ, body, &m)
+		}
+		out = append(out, m)
+
+		if truncated {
+			break
+		}
+		i = end
+	}
+	return out
+}
+
+func decodeAppleContinuity(data []byte) (Frame, bool) {
+	msgs := DecodeAppleContinuity(data)
+	if len(msgs) == 0 {
+		return Frame{}, false
+	}
+	f := Frame{
+		Format: "apple_continuity",
+		Fields: map[string]any{"messages": msgs},
+	}
+	best := 0
+	for _, m := range msgs {
+		if m.rank > best {
+			best = m.rank
+			f.Product = m.product
+		}
+	}
+	return f, true
+}
+
+func decodeAppleMessage(typ byte, b []byte, m *AppleMessage) {
+	fields := map[string]any{}
+	switch typ {
+	case appleIBeacon:
+		if len(b) >= 21 {
+			for k, v := range parseIBeaconBody(b[:21]).Frame().Fields {
+				fields[k] = v
+			}
+		}
+
+	case appleAirDrop:
+		if len(b) >= 18 {
+			fields["version"] = int(b[8])
+			fields["apple_id_hash"] = hexString(b[9:11])
+			fields["phone_hash"] = hexString(b[11:13])
+			fields["email_hash"] = hexString(b[13:15])
+			fields["email2_hash"] = hexString(b[15:17])
+		}
+		m.product, m.rank = "Apple device", rankGeneric
+
+	case appleHomeKit:
+		if len(b) >= 13 {
+			fields["status_flags"] = int(b[0])
+			fields["device_id"] = hexString(b[1:7])
+			fields["category"] = int(binary.LittleEndian.Uint16(b[7:9]))
+			fields["global_state"] = int(binary.LittleEndian.Uint16(b[9:11]))
+			fields["config_number"] = int(b[11])
+			fields["compatible_version"] = int(b[12])
+		}
+
+	case appleProximityPairing:
+		if len(b) >= 9 {
+			model := binary.BigEndian.Uint16(b[1:3])
+			fields["model_id"] = fmt.Sprintf("0x%04X", model)
+			fields["status"] = int(b[3])
+			if pct, ok := appleBatteryNibble(b[4] >> 4); ok {
+				fields["battery_pod1_pct"] = pct
+			}
+			if pct, ok := appleBatteryNibble(b[4] & 0x0f); ok {
+				fields["battery_pod2_pct"] = pct
+			}
+			if pct, ok := appleBatteryNibble(b[5] & 0x0f); ok {
+				fields["battery_case_pct"] = pct
+			}
+			charging := b[5] >> 4
+			fields["charging_pod1"] = charging&0x01 != 0
+			fields["charging_pod2"] = charging&0x02 != 0
+			fields["charging_case"] = charging&0x04 != 0
+			fields["lid_open_count"] = int(b[6])
+			fields["color"] = int(b[7])
+			if name, ok := appleAudioModels[model]; ok {
+				fields["model"] = name
+				m.product, m.rank = name, rankModel
+			} else {
+				m.product, m.rank = "Apple audio device", rankFamily
+			}
+		}
+
+	case appleHeySiri:
+		if len(b) >= 7 {
+			fields["perceptual_hash"] = hexString(b[0:2])
+			fields["snr"] = int(b[2])
+			fields["confidence"] = int(b[3])
+			class := binary.BigEndian.Uint16(b[4:6])
+			fields["device_class"] = fmt.Sprintf("0x%04X", class)
+			if name, ok := appleSiriDeviceClass[class]; ok {
+				fields["device"] = name
+				m.product, m.rank = name, rankFamily
+			}
+		}
+
+	case appleAirPlayTarget:
+		if len(b) >= 6 {
+			fields["flags"] = int(b[0])
+			fields["config_seed"] = int(b[1])
+			fields["ipv4"] = fmt.Sprintf("%d.%d.%d.%d", b[2], b[3], b[4], b[5])
+		}
+
+	case appleMagicSwitch:
+		if len(b) >= 3 {
+			fields["data"] = hexString(b[0:2])
+			fields["confidence"] = int(b[2])
+		}
+		m.product, m.rank = "Apple Watch", rankFamily
+
+	case appleHandoff:
+		if len(b) >= 4 {
+			fields["clipboard"] = int(b[0])
+			fields["sequence"] = int(binary.BigEndian.Uint16(b[1:3]))
+			fields["auth_tag"] = hexString(b[3:4])
+		}
+		m.product, m.rank = "Apple device", rankGeneric
+
+	case appleTetheringTarget:
+		if len(b) >= 4 {
+			fields["icloud_id_hash"] = hexString(b[0:4])
+		}
+		m.product, m.rank = "Apple device", rankGeneric
+
+	case appleTetheringSource:
+		if len(b) >= 6 {
+			fields["version"] = int(b[0])
+			fields["flags"] = int(b[1])
+			fields["battery_pct"] = int(b[2])
+			fields["cell_type"] = int(b[4])
+			fields["cell_bars"] = int(b[5])
+		}
+		// Instant Hotspot sources carry a cellular modem.
+		m.product, m.rank = "iPhone", rankFamily
+
+	case appleNearbyAction:
+		if len(b) >= 2 {
+			fields["action_flags"] = int(b[0])
+			fields["action_type"] = int(b[1])
+			if name, ok := appleNearbyActionTypes[b[1]]; ok {
+				fields["action"] = name
+			}
+			if len(b) >= 5 {
+				fields["auth_tag"] = hexString(b[2:5])
+			}
+		}
+		m.product, m.rank = "Apple device", rankGeneric
+
+	case appleNearbyInfo:
+		if len(b) >= 2 {
+			code := b[0] & 0x0f
+			fields["status_flags"] = int(b[0] >> 4)
+			fields["action_code"] = int(code)
+			if name, ok := appleNearbyActionCodes[code]; ok {
+				fields["action"] = name
+			}
+			fields["data_flags"] = int(b[1])
+			if len(b) >= 5 {
+				fields["auth_tag"] = hexString(b[2:5])
+			}
+		}
+		m.product, m.rank = "Apple device", rankGeneric
+
+	case appleFindMy:
+		if len(b) >= 1 {
+			status := b[0]
+			fields["status"] = int(status)
+			fields["battery"] = appleFindMyBattery[(status>>6)&0x03]
+			devType := appleFindMyDeviceTypes[(status>>4)&0x03]
+			fields["device"] = devType
+			// 25 bytes: separated from owner (full key); 2 bytes: near owner.
+			fields["separated"] = len(b) >= 25
+			if len(b) >= 25 {
+				fields["public_key_fragment"] = hexString(b[1:23])
+				fields["key_bits"] = int(b[23])
+				fields["hint"] = int(b[24])
+			} else if len(b) >= 2 {
+				fields["key_bits"] = int(b[1])
+			}
+			rank := rankFamily
+			if devType == "Apple device" {
+				rank = rankGeneric
+			}
+			m.product, m.rank = devType, rank
+		}
+	}
+	if len(fields) > 0 {
+		m.Fields = fields
+	}
+}
+
+// appleBatteryNibble converts a 0-10 battery nibble to percent; 15 means unknown.
+func appleBatteryNibble(v byte) (int, bool) {
+	if v > 10 {
+		return 0, false
+	}
+	return int(v) * 10, true
+}
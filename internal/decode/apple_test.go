@@ -0,0 +1,197 @@
+package decode
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// The payloads are manufacturer data_hex values (without the company ID) as
+// stored in advertisement_json.
+func TestAppleContinuity(t *testing.T) {
+	// fields lists the decoded fields to check; bare means the message must
+	// have no fields at all.
+	type msg struct {
+		typ    string
+		fields map[string]any
+		bare   bool
+	}
+	tests := []struct {
+		name    string
+		dataHex string
+		product string
+		msgs    []msg
+	}{
+		{
+			name:    "nearby info",
+			dataHex: "10051b1c6e852a",
+			product: "Apple device",
+			msgs: []msg{{typ: "0x10", fields: map[string]any{
+				"status_flags": 1,
+				"action_code":  0x0b,
+				"action":       "recent user interaction",
+				"data_flags":   0x1c,
+				"auth_tag":     "6e852a",
+			}}},
+		},
+		{
+			name:    "handoff and nearby info",
+			dataHex: "0c0e 00 1f2a 9c 5b4e3d2c1b0a9f8e7d6c 1005071c6e852a",
+			product: "Apple device",
+			msgs: []msg{
+				{typ: "0x0C", fields: map[string]any{"clipboard": 0, "sequence": 0x1f2a, "auth_tag": "9c"}},
+				{typ: "0x10", fields: map[string]any{"action_code": 7, "action": "active user, screen on"}},
+			},
+		},
+		{
+			name:    "airpods pro proximity pairing",
+			dataHex: "071901 0e20 2b 99 8f 01 00 0045a1b2c3d4e5f60718293a4b5c6d7e8f",
+			product: "AirPods Pro",
+			msgs: []msg{{typ: "0x07", fields: map[string]any{
+				"model_id":         "0x0E20",
+				"model":            "AirPods Pro",
+				"status":           0x2b,
+				"battery_pod1_pct": 90,
+				"battery_pod2_pct": 90,
+				"charging_pod1":    false,
+				"charging_case":    false,
+				"lid_open_count":   1,
+				"color":            0,
+			}}},
+		},
+		{
+			name:    "unknown audio model",
+			dataHex: "071901 9920 2b 5a 15 03 01 0045a1b2c3d4e5f60718293a4b5c6d7e8f",
+			product: "Apple audio device",
+			msgs: []msg{{typ: "0x07", fields: map[string]any{
+				"model_id":         "0x9920",
+				"battery_pod1_pct": 50,
+				"battery_case_pct": 50,
+				"charging_pod1":    true,
+			}}},
+		},
+		{
+			name:    "airtag separated from owner",
+			dataHex: "1219 10 a1b2c3d4e5f60718293a4b5c6d7e8f90011223344556 02 00",
+			product: "AirTag",
+			msgs: []msg{{typ: "0x12", fields: map[string]any{
+				"device":              "AirTag",
+				"battery":             "full",
+				"separated":           true,
+				"public_key_fragment": "a1b2c3d4e5f60718293a4b5c6d7e8f90011223344556",
+				"key_bits":            2,
+				"hint":                0,
+			}}},
+		},
+		{
+			name:    "find my near owner",
+			dataHex: "12028002",
+			product: "Apple device",
+			msgs: []msg{{typ: "0x12", fields: map[string]any{
+				"device":    "Apple device",
+				"battery":   "low",
+				"separated": false,
+				"key_bits":  2,
+			}}},
+		},
+		{
+			name:    "airdrop",
+			dataHex: "0512 0000000000000000 01 1a2b 3c4d 5e6f 7a8b 00",
+			product: "Apple device",
+			msgs: []msg{{typ: "0x05", fields: map[string]any{
+				"version":       1,
+				"apple_id_hash": "1a2b",
+				"phone_hash":    "3c4d",
+				"email_hash":    "5e6f",
+				"email2_hash":   "7a8b",
+			}}},
+		},
+		{
+			name:    "tethering source beats nearby info",
+			dataHex: "10051b1c6e852a 0e06 01 00 64 00 02 04",
+			product: "iPhone",
+			msgs: []msg{
+				{typ: "0x10"},
+				{typ: "0x0E", fields: map[string]any{"version": 1, "battery_pct": 100, "cell_type": 2, "cell_bars": 4}},
+			},
+		},
+		{
+			name:    "tethering target",
+			dataHex: "0d04a1b2c3d4",
+			product: "Apple device",
+			msgs:    []msg{{typ: "0x0D", fields: map[string]any{"icloud_id_hash": "a1b2c3d4"}}},
+		},
+		{
+			name:    "truncated tlv",
+			dataHex: "10051b1c",
+			msgs:    []msg{{typ: "0x10", fields: map[string]any{"truncated": true}}},
+		},
+		{
+			name:    "truncated tlv after a complete one",
+			dataHex: "0d04a1b2c3d4 1219 10a1b2",
+			product: "Apple device",
+			msgs: []msg{
+				{typ: "0x0D"},
+				{typ: "0x12", fields: map[string]any{"truncated": true}},
+			},
+		},
+		{
+			name:    "body too short for the message type",
+			dataHex: "0703010e20",
+			msgs:    []msg{{typ: "0x07", bare: true}},
+		},
+		{
+			name:    "empty find my body",
+			dataHex: "1200",
+			msgs:    []msg{{typ: "0x12", bare: true}},
+		},
+		{
+			name:    "trailing type byte without length",
+			dataHex: "0d04a1b2c3d4 10",
+			product: "Apple device",
+			msgs:    []msg{{typ: "0x0D"}},
+		},
+		{
+			name:    "unknown message type",
+			dataHex: "1e03010203",
+			msgs:    []msg{{typ: "0x1E", bare: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(strings.ReplaceAll(tt.dataHex, " ", ""))
+			if err != nil {
+				t.Fatalf("bad test payload: %v", err)
+			}
+			frames := Manufacturer(CompanyApple, data)
+			if len(frames) != 1 {
+				t.Fatalf("got %d frames, want 1", len(frames))
+			}
+			f := frames[0]
+			if f.Format != "apple_continuity" || f.Key != "0x004C" {
+				t.Fatalf("got format %q key %q", f.Format, f.Key)
+			}
+			if got := Finish(frames).Product; got != tt.product {
+				t.Errorf("product = %q, want %q", got, tt.product)
+			}
+			got, _ := f.Fields["messages"].([]AppleMessage)
+			if len(got) != len(tt.msgs) {
+				t.Fatalf("got %d messages %+v, want %d", len(got), got, len(tt.msgs))
+			}
+			for i, want := range tt.msgs {
+				m := got[i]
+				if m.TypeHex != want.typ {
+					t.Errorf("message %d: type %s, want %s", i, m.TypeHex, want.typ)
+				}
+				if want.bare && len(m.Fields) != 0 {
+					t.Errorf("message %d: unexpected fields %v", i, m.Fields)
+				}
+				for k, v := range want.fields {
+					if m.Fields[k] != v {
+						t.Errorf("message %d: %s = %v (%T), want %v (%T)", i, k, m.Fields[k], m.Fields[k], v, v)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package decode
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// Beacon kinds.
+const (
+	BeaconIBeacon      = "ibeacon"
+	BeaconAltBeacon    = "altbeacon"
+	BeaconEddystoneUID = "eddystone_uid"
+	BeaconEddystoneURL = "eddystone_url"
+	BeaconEddystoneTLM = "eddystone_tlm"
+	BeaconEddystoneEID = "eddystone_eid"
+)
+
+// ServiceEddystone is the 16-bit service UUID Eddystone frames are published under.
+const ServiceEddystone uint16 = 0xFEAA
+
+// Beacon holds the structured fields of a decoded beacon frame.
+// Only the fields relevant to Kind are set.
+type Beacon struct {
+	Kind string `json:"kind"`
+
+	// iBeacon / AltBeacon.
+	UUID      string `json:"uuid,omitempty"`
+	Major     *int   `json:"major,omitempty"`
+	Minor     *int   `json:"minor,omitempty"`
+	CompanyID *int   `json:"company_id,omitempty"`
+
+	// Measured power at 1 m (iBeacon/AltBeacon) or at 0 m (Eddystone), dBm.
+	TxPower *int `json:"tx_power,omitempty"`
+
+	// Eddystone-UID / URL / EID.
+	Namespace string `json:"namespace,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	URL       string `json:"url,omitempty"`
+	EID       string `json:"eid,omitempty"`
+
+	// Eddystone-TLM (unencrypted).
+	TLMVersion   *int     `json:"tlm_version,omitempty"`
+	BatteryMV    *int     `json:"battery_mv,omitempty"`
+	TemperatureC *float64 `json:"temperature_c,omitempty"`
+	AdvCount     *int64   `json:"adv_count,omitempty"`
+	UptimeS      *float64 `json:"uptime_s,omitempty"`
+}
+
+// ParseIBeacon parses an iBeacon from Apple manufacturer data
+// (02 15 <uuid:16> <major:2> <minor:2> <power:1>).
+func ParseIBeacon(payload []byte) (Beacon, bool) {
+	if len(payload) < 23 || payload[0] != 0x02 || payload[1] != 0x15 {
+		return Beacon{}, false
+	}
+	return parseIBeaconBody(payload[2:23]), true
+}
+
+func parseIBeaconBody(b []byte) Beacon {
+	major := int(binary.BigEndian.Uint16(b[16:18]))
+	minor := int(binary.BigEndian.Uint16(b[18:20]))
+	power := int(int8(b[20]))
+	return Beacon{
+		Kind:    BeaconIBeacon,
+		UUID:    formatUUID(b[0:16]),
+		Major:   &major,
+		Minor:   &minor,
+		TxPower: &power,
+	}
+}
+
+// ParseAltBeacon parses an AltBeacon from manufacturer data of any company
+// (BE AC <beacon id:20> <ref rssi:1> <reserved:1>).
+// The beacon ID is reported as UUID + major + minor, the common layout.
+func ParseAltBeacon(companyID uint16, payload []byte) (Beacon, bool) {
+	if len(payload) < 24 || payload[0] != 0xBE || payload[1] != 0xAC {
+		return Beacon{}, false
+	}
+	cid := int(companyID)
+	major := int(binary.BigEndian.Uint16(payload[18:20]))
+	minor := int(binary.BigEndian.Uint16(payload[20:22]))
+	power := int(int8(payload[22]))
+	return Beacon{
+		Kind:      BeaconAltBeacon,
+		UUID:      formatUUID(payload[2:18]),
+		Major:     &major,
+		Minor:     &minor,
+		CompanyID: &cid,
+		TxPower:   &power,
+	}, true
+}
+
+// ParseEddystone parses an Eddystone frame from service data UUID 0xFEAA.
+func ParseEddystone(data []byte) (Beacon, bool) {
+	if len(data) < 2 {
+		return Beacon{}, false
+	}
+	switch data[0] {
+	case 0x00: // UID
+		if len(data) < 18 {
+			return Beacon{}, false
+		}
+		power := int(int8(data[1]))
+		return Beacon{
+			Kind:      BeaconEddystoneUID,
+			TxPower:   &power,
+			Namespace: hexString(data[2:12]),
+			Instance:  hexString(data[12:18]),
+		}, true
+
+	case 0x10: // URL
+		if len(data) < 3 {
+			return Beacon{}, false
+		}
+		url, ok := decodeEddystoneURL(data[2], data[3:])
+		if !ok {
+			return Beacon{}, false
+		}
+		power := int(int8(data[1]))
+		return Beacon{Kind: BeaconEddystoneURL, TxPower: &power, URL: url}, true
+
+	case 0x20: // TLM
+		version := int(data[1])
+		b := Beacon{Kind: BeaconEddystoneTLM, TLMVersion: &version}
+		// Version 0x01 is encrypted (eTLM); only the version is meaningful.
+		if version != 0x00 {
+			return b, true
+		}
+		if len(data) < 14 {
+			return Beacon{}, false
+		}
+		if mv := int(binary.BigEndian.Uint16(data[2:4])); mv != 0 {
+			b.BatteryMV = &mv
+		}
+		if raw := binary.BigEndian.Uint16(data[4:6]); raw != 0x8000 {
+			t := float64(int16(raw)) / 256.0
+			b.TemperatureC = &t
+		}
+		cnt := int64(binary.BigEndian.Uint32(data[6:10]))
+		b.AdvCount = &cnt
+		up := float64(binary.BigEndian.Uint32(data[10:14])) / 10.0
+		b.UptimeS = &up
+		return b, true
+
+	case 0x30: // EID
+		if len(data) < 10 {
+			return Beacon{}, false
+		}
+		power := int(int8(data[1]))
+		return Beacon{Kind: BeaconEddystoneEID, TxPower: &power, EID: hexString(data[2:10])}, true
+	}
+	return Beacon{}, false
+}
+
+var eddystoneSchemes = []string{"http://www.", "https://www.", "http://", "https://"}
+
+var eddystoneExpansions = []string{
+	".com/", ".org/", ".edu/", ".net/", ".info/", ".biz/", ".gov/",
+	".com", ".org", ".edu", ".net", ".info", ".biz", ".gov",
+}
+
+func decodeEddystoneURL(scheme byte, enc []byte) (string, bool) {
+	if int(scheme) >= len(eddystoneSchemes) {
+		return "", false
+	}
+	var sb strings.Builder
+	sb.WriteString(eddystoneSchemes[scheme])
+	for _, c := range enc {
+		switch {
+		case int(c) < len(eddystoneExpansions):
+			sb.WriteString(eddystoneExpansions[c])
+		case c > 0x20 && c < 0x7f:
+			sb.WriteByte(c)
+		default:
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+// Frame converts the beacon into a decoded frame.
+func (b Beacon) Frame() Frame {
+	fields := map[string]any{}
+	if b.UUID != "" {
+		fields["uuid"] = b.UUID
+	}
+	if b.Major != nil {
+		fields["major"] = *b.Major
+	}
+	if b.Minor != nil {
+		fields["minor"] = *b.Minor
+	}
+	if b.CompanyID != nil {
+		fields["company_id"] = *b.CompanyID
+	}
+	if b.TxPower != nil {
+		fields["tx_power"] = *b.TxPower
+	}
+	if b.Namespace != "" {
+		fields["namespace"] = b.Namespace
+	}
+	if b.Instance != "" {
+		fields["instance"] = b.Instance
+	}
+	if b.URL != "" {
+		fields["url"] = b.URL
+	}
+	if b.EID != "" {
+		fields["eid"] = b.EID
+	}
+	if b.TLMVersion != nil {
+		fields["tlm_version"] = *b.TLMVersion
+	}
+	if b.BatteryMV != nil {
+		fields["battery_mv"] = *b.BatteryMV
+	}
+	if b.TemperatureC != nil {
+		fields["temperature_c"] = *b.TemperatureC
+	}
+	if b.AdvCount != nil {
+		fields["adv_count"] = *b.AdvCount
+	}
+	if b.UptimeS != nil {
+		fields["uptime_s"] = *b.UptimeS
+	}
+	bb := b
+	return Frame{Format: b.Kind, Fields: fields, beacon: &bb}
+}
@@ -0,0 +1,36 @@
+package decode
+
+// Built-in decoders. Registration order matters when several decoders share
+// a key, so all of them are registered here rather than in each file.
+func init() {
+	// Beacons.
+	RegisterAnyManufacturer(func(companyID uint16, data []byte) (Frame, bool) {
+		b, ok := ParseAltBeacon(companyID, data)
+		return b.Frame(), ok
+	})
+	RegisterManufacturer(CompanyApple, func(_ uint16, data []byte) (Frame, bool) {
+		b, ok := ParseIBeacon(data)
+		return b.Frame(), ok
+	})
+	RegisterServiceData(UUID128(ServiceEddystone), func(data []byte) (Frame, bool) {
+		b, ok := ParseEddystone(data)
+		return b.Frame(), ok
+	})
+
+	// Apple Continuity (after iBeacon, which shares the company ID).
+	RegisterManufacturer(CompanyApple, func(_ uint16, data []byte) (Frame, bool) {
+		return decodeAppleContinuity(data)
+	})
+
+	// Pairing and proximity families.
+	RegisterServiceData(UUID128(ServiceFastPair), decodeFastPair)
+	RegisterServiceData(UUID128(ServiceExposureNotification), decodeExposureNotification)
+	RegisterManufacturer(CompanyMicrosoft, decodeMicrosoft)
+	RegisterManufacturer(CompanySamsung, decodeSamsung)
+
+	// Environmental sensors.
+	RegisterServiceData(UUID128(ServiceBTHome), decodeBTHome)
+	RegisterServiceData(UUID128(ServiceXiaomi), decodeMiBeacon)
+	RegisterManufacturer(CompanyRuuvi, decodeRuuvi)
+	RegisterManufacturer(CompanyGovee, decodeGovee)
+}
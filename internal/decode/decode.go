@@ -0,0 +1,191 @@
+package decode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Frame is one decoded manufacturer or service data payload.
+//
+// Source is "manufacturer" or "service_data"; Key is the company ID
+// (e.g. "0x004C") or the service UUID the payload was published under.
+type Frame struct {
+	Source  string         `json:"source"`
+	Key     string         `json:"key"`
+	Format  string         `json:"format"`
+	Fields  map[string]any `json:"fields,omitempty"`
+	Product string         `json:"product,omitempty"`
+
+	beacon   *Beacon
+	readings []Reading
+}
+
+// Reading is one sensor value extracted from a broadcast.
+type Reading struct {
+	Metric string  `json:"metric"`
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+}
+
+// Result holds all frames decoded from one advertisement.
+type Result struct {
+	Frames []Frame `json:"frames,omitempty"`
+	// Product is the most specific device type derived from the frames
+	// (e.g. "AirPods Pro", "iPhone"). Empty when nothing could be derived.
+	Product string `json:"product,omitempty"`
+	// Beacons lists the beacon frames in structured form.
+	Beacons []Beacon `json:"-"`
+	// Readings lists sensor values from all frames, tagged with the frame format.
+	Readings []SourcedReading `json:"-"`
+}
+
+// SourcedReading is a Reading together with the decoder format it came from.
+type SourcedReading struct {
+	Reading
+	Format string
+}
+
+// Empty reports whether nothing was decoded.
+func (r Result) Empty() bool {
+	return len(r.Frames) == 0
+}
+
+// Manufacturer decodes a manufacturer specific data payload (without the
+// company ID prefix). It returns nil when no decoder recognizes the payload.
+func Manufacturer(companyID uint16, data []byte) []Frame {
+	if len(data) == 0 {
+		return nil
+	}
+	f, ok := decodeManufacturer(companyID, data)
+	if !ok {
+		return nil
+	}
+	f.Source = "manufacturer"
+	f.Key = fmt.Sprintf("0x%04X", companyID)
+	return []Frame{f}
+}
+
+// ServiceData decodes a service data payload published under the given
+// service UUID (16-bit or 128-bit string form).
+func ServiceData(uuid string, data []byte) []Frame {
+	if len(data) == 0 {
+		return nil
+	}
+	u := CanonicalUUID(uuid)
+	if u == "" {
+		return nil
+	}
+	f, ok := decodeServiceData(u, data)
+	if !ok {
+		return nil
+	}
+	f.Source = "service_data"
+	if u16, ok := UUID16(u); ok {
+		f.Key = fmt.Sprintf("0x%04X", u16)
+	} else {
+		f.Key = u
+	}
+	return []Frame{f}
+}
+
+// Finish derives the overall product from the decoded frames.
+// The first frame with a product wins; decoders only set Product when they
+// are specific about it.
+func Finish(frames []Frame) Result {
+	res := Result{Frames: frames}
+	for _, f := range frames {
+		if res.Product == "" && strings.TrimSpace(f.Product) != "" {
+			res.Product = f.Product
+		}
+		if f.beacon != nil {
+			res.Beacons = append(res.Beacons, *f.beacon)
+		}
+		for _, r := range f.readings {
+			res.Readings = append(res.Readings, SourcedReading{Reading: r, Format: f.Format})
+		}
+	}
+	return res
+}
+
+// WithReadings attaches sensor readings to a frame and mirrors them into
+// Fields so they also appear in advertisement_json. A metric that occurs more
+// than once (e.g. two BTHome temperature objects) is stored as metric,
+// metric_2, metric_3, ... in broadcast order.
+func (f Frame) WithReadings(readings []Reading) Frame {
+	if len(readings) == 0 {
+		return f
+	}
+	if f.Fields == nil {
+		f.Fields = map[string]any{}
+	}
+	seen := make(map[string]int, len(f.readings)+len(readings))
+	for _, r := range f.readings {
+		seen[r.Metric]++
+	}
+	for _, r := range readings {
+		seen[r.Metric]++
+		key := r.Metric
+		if n := seen[r.Metric]; n > 1 {
+			key = fmt.Sprintf("%s_%d", r.Metric, n)
+		}
+		f.Fields[key] = r.Value
+	}
+	f.readings = append(f.readings, readings...)
+	return f
+}
+
+// UUID16 returns the 16-bit short form of a UUID built on the Bluetooth base
+// UUID. ok is false for vendor-specific 128-bit UUIDs.
+func UUID16(uuid string) (uint16, bool) {
+	u := strings.ToLower(strings.TrimSpace(uuid))
+	u = strings.TrimPrefix(u, "0x")
+	var v uint32
+	switch {
+	case len(u) == 4:
+		if _, err := fmt.Sscanf(u, "%04x", &v); err != nil {
+			return 0, false
+		}
+		return uint16(v), true
+	case len(u) == 36 && strings.HasSuffix(u, "-0000-1000-8000-00805f9b34fb") && strings.HasPrefix(u, "0000"):
+		if _, err := fmt.Sscanf(u[4:8], "%04x", &v); err != nil {
+			return 0, false
+		}
+		return uint16(v), true
+	}
+	return 0, false
+}
+
+// UUID128 expands a 16-bit SIG UUID to its canonical 128-bit lower-case form.
+func UUID128(u16 uint16) string {
+	return fmt.Sprintf("0000%04x-0000-1000-8000-00805f9b34fb", u16)
+}
+
+// CanonicalUUID returns the 128-bit lower-case form of a 16-bit or 128-bit
+// UUID string, or "" when it cannot be parsed.
+func CanonicalUUID(uuid string) string {
+	if u16, ok := UUID16(uuid); ok {
+		return UUID128(u16)
+	}
+	u := strings.ToLower(strings.TrimSpace(uuid))
+	if len(u) == 36 && strings.Count(u, "-") == 4 {
+		return u
+	}
+	return ""
+}
+
+func hexString(b []byte) string {
+	const h = "0123456789abcdef"
+	out := make([]byte, 0, len(b)*2)
+	for _, v := range b {
+		out = append(out, h[v>>4], h[v&0x0f])
+	}
+	return string(out)
+}
+
+func formatUUID(b []byte) string {
+	if len(b) != 16 {
+		return ""
+	}
+	s := hexString(b)
+	return strings.ToUpper(s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32])
+}
@@ -0,0 +1,63 @@
+package decode
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestWithReadingsRepeatedMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    func() Frame
+		fields   map[string]any
+		readings int
+	}{
+		{
+			name: "bthome with two temperatures and three acceleration objects",
+			frame: func() Frame {
+				data, _ := hex.DecodeString(strings.ReplaceAll("40 02c409 023c0a 51e803 51d007 51b80b", " ", ""))
+				frames := ServiceData("fcd2", data)
+				if len(frames) != 1 {
+					t.Fatalf("got %d frames, want 1", len(frames))
+				}
+				return frames[0]
+			},
+			fields: map[string]any{
+				"temperature":    25.0,
+				"temperature_2":  26.2,
+				"acceleration":   1.0,
+				"acceleration_2": 2.0,
+				"acceleration_3": 3.0,
+			},
+			readings: 5,
+		},
+		{
+			name: "numbering continues across calls",
+			frame: func() Frame {
+				return Frame{}.
+					WithReadings([]Reading{{Metric: "humidity", Value: 40}}).
+					WithReadings([]Reading{{Metric: "humidity", Value: 41}, {Metric: "battery", Value: 90}})
+			},
+			fields: map[string]any{
+				"humidity":   40.0,
+				"humidity_2": 41.0,
+				"battery":    90.0,
+			},
+			readings: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.frame()
+			for k, v := range tt.fields {
+				if f.Fields[k] != v {
+					t.Errorf("%s = %v, want %v", k, f.Fields[k], v)
+				}
+			}
+			if got := len(Finish([]Frame{f}).Readings); got != tt.readings {
+				t.Errorf("got %d readings, want %d", got, tt.readings)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package decode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Standard GATT characteristics with typed decoders (16-bit UUIDs).
+const (
+	CharDeviceName           uint16 = 0x2A00
+	CharAppearance           uint16 = 0x2A01
+	CharPPCP                 uint16 = 0x2A04
+	CharServiceChanged       uint16 = 0x2A05
+	CharTxPowerLevel         uint16 = 0x2A07
+	CharDateTime             uint16 = 0x2A08
+	CharBatteryLevel         uint16 = 0x2A19
+	CharTemperatureMeas      uint16 = 0x2A1C
+	CharTemperatureType      uint16 = 0x2A1D
+	CharIntermediateTemp     uint16 = 0x2A1E
+	CharSystemID             uint16 = 0x2A23
+	CharModelNumber          uint16 = 0x2A24
+	CharSerialNumber         uint16 = 0x2A25
+	CharFirmwareRevision     uint16 = 0x2A26
+	CharHardwareRevision     uint16 = 0x2A27
+	CharSoftwareRevision     uint16 = 0x2A28
+	CharManufacturerName     uint16 = 0x2A29
+	CharCurrentTime          uint16 = 0x2A2B
+	CharHeartRateMeasurement uint16 = 0x2A37
+	CharBodySensorLocation   uint16 = 0x2A38
+	CharPnPID                uint16 = 0x2A50
+	CharPressure             uint16 = 0x2A6D
+	CharTemperature          uint16 = 0x2A6E
+	CharHumidity             uint16 = 0x2A6F
+	CharCentralAddrRes       uint16 = 0x2AA6
+)
+
+type charDecoder func(b []byte) (map[string]any, bool)
+
+var charDecoders = map[uint16]charDecoder{
+	CharDeviceName:           decodeCharString,
+	CharAppearance:           decodeCharAppearance,
+	CharPPCP:                 decodeCharPPCP,
+	CharServiceChanged:       decodeCharServiceChanged,
+	CharTxPowerLevel:         decodeCharTxPower,
+	CharDateTime:             decodeCharDateTime,
+	CharBatteryLevel:         decodeCharBatteryLevel,
+	CharTemperatureMeas:      decodeCharTemperatureMeasurement,
+	CharTemperatureType:      decodeCharTemperatureType,
+	CharIntermediateTemp:     decodeCharTemperatureMeasurement,
+	CharSystemID:             decodeCharSystemID,
+	CharModelNumber:          decodeCharString,
+	CharSerialNumber:         decodeCharString,
+	CharFirmwareRevision:     decodeCharString,
+	CharHardwareRevision:     decodeCharString,
+	CharSoftwareRevision:     decodeCharString,
+	CharManufacturerName:     decodeCharString,
+	CharCurrentTime:          decodeCharDateTime,
+	CharHeartRateMeasurement: decodeCharHeartRate,
+	CharBodySensorLocation:   decodeCharBodySensorLocation,
+	CharPnPID:                decodeCharPnPID,
+	CharPressure:             decodeCharPressure,
+	CharTemperature:          decodeCharTemperature,
+	CharHumidity:             decodeCharHumidity,
+	CharCentralAddrRes:       decodeCharBool,
+}
+
+// Characteristic decodes the value of a standard GATT characteristic. uuid
+// may be the 16-bit or 128-bit form. It returns false for unknown
+// characteristics and malformed values.
+func Characteristic(uuid string, value []byte) (map[string]any, bool) {
+	u16, ok := UUID16(uuid)
+	if !ok {
+		return nil, false
+	}
+	dec, ok := charDecoders[u16]
+	if !ok {
+		return nil, false
+	}
+	return dec(value)
+}
+
+func decodeCharString(b []byte) (map[string]any, bool) {
+	s := strings.TrimSpace(strings.TrimRight(string(b), "\x00"))
+	if s == "" {
+		return nil, false
+	}
+	return map[string]any{"value": s}, true
+}
+
+func decodeCharBool(b []byte) (map[string]any, bool) {
+	if len(b) < 1 {
+		return nil, false
+	}
+	return map[string]any{"value": b[0] != 0}, true
+}
+
+func decodeCharAppearance(b []byte) (map[string]any, bool) {
+	if len(b) < 2 {
+		return nil, false
+	}
+	v := binary.LittleEndian.Uint16(b)
+	return map[string]any{"value": int(v), "category": int(v >> 6), "subcategory": int(v & 0x3F)}, true
+}
+
+// Peripheral Preferred Connection Parameters: intervals in 1.25 ms units,
+// supervision timeout in 10 ms units (0xFFFF = no specific value).
+func decodeCharPPCP(b []byte) (map[string]any, bool) {
+	if len(b) < 8 {
+		return nil, false
+	}
+	out := map[string]any{}
+	if v := binary.LittleEndian.Uint16(b[0:]); v != 0xFFFF {
+		out["min_interval_ms"] = float64(v) * 1.25
+	}
+	if v := binary.LittleEndian.Uint16(b[2:]); v != 0xFFFF {
+		out["max_interval_ms"] = float64(v) * 1.25
+	}
+	if v := binary.LittleEndian.Uint16(b[4:]); v != 0xFFFF {
+		out["peripheral_latency"] = int(v)
+	}
+	if v := binary.LittleEndian.Uint16(b[6:]); v != 0xFFFF {
+		out["supervision_timeout_ms"] = int(v) * 10
+	}
+	return out, true
+}
+
+func decodeCharServiceChanged(b []byte) (map[string]any, bool) {
+	if len(b) < 4 {
+		return nil, false
+	}
+	return map[string]any{
+		"start_handle": int(binary.LittleEndian.Uint16(b[0:])),
+		"end_handle":   int(binary.LittleEndian.Uint16(b[2:])),
+	}, true
+}
+
+func decodeCharTxPower(b []byte) (map[string]any, bool) {
+	if len(b) < 1 {
+		return nil, false
+	}
+	return map[string]any{"tx_power_dbm": int(int8(b[0]))}, true
+}
+
+// Date Time (and the first 7 bytes of Current Time).
+func decodeCharDateTime(b []byte) (map[string]any, bool) {
+	if len(b) < 7 {
+		return nil, false
+	}
+	year := binary.LittleEndian.Uint16(b)
+	out := map[string]any{
+		"value": fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, b[2], b[3], b[4], b[5], b[6]),
+	}
+	if len(b) >= 8 && b[7] >= 1 && b[7] <= 7 {
+		out["day_of_week"] = int(b[7])
+	}
+	return out, true
+}
+
+func decodeCharBatteryLevel(b []byte) (map[string]any, bool) {
+	if len(b) < 1 || b[0] > 100 {
+		return nil, false
+	}
+	return map[string]any{"battery_pct": int(b[0])}, true
+}
+
+var temperatureTypes = map[byte]string{
+	1: "armpit", 2: "body", 3: "ear", 4: "finger", 5: "gastro-intestinal tract",
+	6: "mouth", 7: "rectum", 8: "toe", 9: "tympanum",
+}
+
+// Temperature Measurement / Intermediate Temperature: flags, IEEE-11073
+// 32-bit FLOAT, optional time stamp and temperature type.
+func decodeCharTemperatureMeasurement(b []byte) (map[string]any, bool) {
+	if len(b) < 5 {
+		return nil, false
+	}
+	flags := b[0]
+	v, ok := ieee11073Float(binary.LittleEndian.Uint32(b[1:]))
+	if !ok {
+		return nil, false
+	}
+	out := map[string]any{}
+	if flags&0x01 != 0 {
+		out["temperature_f"] = v
+	} else {
+		out["temperature_c"] = v
+	}
+	i := 5
+	if flags&0x02 != 0 {
+		if len(b) < i+7 {
+			return out, true
+		}
+		if ts, ok := decodeCharDateTime(b[i : i+7]); ok {
+			out["timestamp"] = ts["value"]
+		}
+		i += 7
+	}
+	if flags&0x04 != 0 && len(b) > i {
+		if name, ok := temperatureTypes[b[i]]; ok {
+			out["location"] = name
+		}
+	}
+	return out, true
+}
+
+func decodeCharTemperatureType(b []byte) (map[string]any, bool) {
+	if len(b) < 1 {
+		return nil, false
+	}
+	name, ok := temperatureTypes[b[0]]
+	if !ok {
+		return nil, false
+	}
+	return map[string]any{"location": name}, true
+}
+
+// System ID: 40-bit manufacturer identifier followed by a 24-bit OUI, both
+// little-endian.
+func decodeCharSystemID(b []byte) (map[string]any, bool) {
+	if len(b) < 8 {
+		return nil, false
+	}
+	var mfr uint64
+	for i := 4; i >= 0; i-- {
+		mfr = mfr<<8 | uint64(b[i])
+	}
+	oui := uint32(b[5]) | uint32(b[6])<<8 | uint32(b[7])<<16
+	return map[string]any{
+		"manufacturer_id": fmt.Sprintf("%010X", mfr),
+		"oui":             fmt.Sprintf("%06X", oui),
+	}, true
+}
+
+var bodySensorLocations = map[byte]string{
+	0: "other", 1: "chest", 2: "wrist", 3: "finger", 4: "hand", 5: "ear lobe", 6: "foot",
+}
+
+// Heart Rate Measurement: flags, 8/16-bit BPM, sensor contact, optional
+// energy expended (kJ) and RR intervals (1/1024 s).
+func decodeCharHeartRate(b []byte) (map[string]any, bool) {
+	if len(b) < 2 {
+		return nil, false
+	}
+	flags := b[0]
+	out := map[string]any{}
+	i := 1
+	if flags&0x01 != 0 {
+		if len(b) < 3 {
+			return nil, false
+		}
+		out["heart_rate_bpm"] = int(binary.LittleEndian.Uint16(b[1:]))
+		i = 3
+	} else {
+		out["heart_rate_bpm"] = int(b[1])
+		i = 2
+	}
+	if flags&0x04 != 0 {
+		out["sensor_contact"] = flags&0x02 != 0
+	}
+	if flags&0x08 != 0 {
+		if len(b) < i+2 {
+			return out, true
+		}
+		out["energy_expended_kj"] = int(binary.LittleEndian.Uint16(b[i:]))
+		i += 2
+	}
+	if flags&0x10 != 0 {
+		rr := make([]float64, 0, (len(b)-i)/2)
+		for ; i+2 <= len(b); i += 2 {
+			rr = append(rr, math.Round(float64(binary.LittleEndian.Uint16(b[i:]))/1024*1e4)/1e4)
+		}
+		if len(rr) > 0 {
+			out["rr_intervals_s"] = rr
+		}
+	}
+	return out, true
+}
+
+func decodeCharBodySensorLocation(b []byte) (map[string]any, bool) {
+	if len(b) < 1 {
+		return nil, false
+	}
+	name, ok := bodySensorLocations[b[0]]
+	if !ok {
+		return nil, false
+	}
+	return map[string]any{"location": name}, true
+}
+
+var pnpVendorSources = map[byte]string{1: "bluetooth", 2: "usb"}
+
+// PnP ID: vendor ID source, vendor ID, product ID, product version.
+func decodeCharPnPID(b []byte) (map[string]any, bool) {
+	if len(b) < 7 {
+		return nil, false
+	}
+	out := map[string]any{
+		"vendor_id_source": int(b[0]),
+		"vendor_id":        fmt.Sprintf("0x%04X", binary.LittleEndian.Uint16(b[1:])),
+		"product_id":       fmt.Sprintf("0x%04X", binary.LittleEndian.Uint16(b[3:])),
+		"product_version":  fmt.Sprintf("0x%04X", binary.LittleEndian.Uint16(b[5:])),
+	}
+	if s, ok := pnpVendorSources[b[0]]; ok {
+		out["vendor_source"] = s
+	}
+	return out, true
+}
+
+// Environmental Sensing: pressure in 0.1 Pa, temperature in 0.01 °C,
+// humidity in 0.01 %.
+func decodeCharPressure(b []byte) (map[string]any, bool) {
+	if len(b) < 4 {
+		return nil, false
+	}
+	return map[string]any{"pressure_hpa": math.Round(float64(binary.LittleEndian.Uint32(b))/1000*1e3) / 1e3}, true
+}
+
+func decodeCharTemperature(b []byte) (map[string]any, bool) {
+	if len(b) < 2 {
+		return nil, false
+	}
+	v := int16(binary.LittleEndian.Uint16(b))
+	if v == math.MinInt16 {
+		return nil, false // value is not known
+	}
+	return map[string]any{"temperature_c": float64(v) / 100}, true
+}
+
+func decodeCharHumidity(b []byte) (map[string]any, bool) {
+	if len(b) < 2 {
+		return nil, false
+	}
+	v := binary.LittleEndian.Uint16(b)
+	if v == 0xFFFF {
+		return nil, false
+	}
+	return map[string]any{"humidity_pct": float64(v) / 100}, true
+}
+
+// ieee11073Float decodes a 32-bit IEEE-11073 FLOAT (8-bit exponent,
+// 24-bit mantissa). Special values (NaN, infinities) are rejected.
+func ieee11073Float(raw uint32) (float64, bool) {
+	mantissa := int32(raw<<8) >> 8
+	exponent := int8(raw >> 24)
+	switch mantissa {
+	case 0x007FFFFF, -0x00800000, 0x007FFFFE, -0x007FFFFE, -0x007FFFFF:
+		return 0, false
+	}
+	v := float64(mantissa) * math.Pow(10, float64(exponent))
+	return math.Round(v*1e6) / 1e6, true
+}
@@ -0,0 +1,79 @@
+package decode
+
+import "fmt"
+
+// Google service data keys.
+const (
+	ServiceFastPair             uint16 = 0xFE2C
+	ServiceExposureNotification uint16 = 0xFD6F
+)
+
+// Known Fast Pair model IDs. The full list is only available through the
+// Fast Pair device registry; these are the ones seen most often.
+var fastPairModels = map[uint32]string{
+	0x000047: "Arduino 101",
+	0x0001F0: "Bisto CSR8670 Dev Board",
+	0x00000A: "Fast Pair Anti-Spoof Test",
+	0x92BBBD: "Pixel Buds",
+}
+
+// decodeFastPair parses Fast Pair service data. A 3-byte payload is the
+// model ID of a discoverable device; longer payloads carry the account key
+// filter of a device that is not in pairing mode.
+func decodeFastPair(data []byte) (Frame, bool) {
+	f := Frame{Format: "google_fast_pair", Fields: map[string]any{}}
+	if len(data) == 3 {
+		model := uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2])
+		f.Fields["discoverable"] = true
+		f.Fields["model_id"] = fmt.Sprintf("0x%06X", model)
+		if name, ok := fastPairModels[model]; ok {
+			f.Fields["model"] = name
+			f.Product = name
+		} else {
+			f.Product = "Fast Pair accessory"
+		}
+		return f, true
+	}
+	if len(data) < 2 {
+		return Frame{}, false
+	}
+	f.Fields["discoverable"] = false
+	f.Fields["flags"] = int(data[0])
+	// Length/type byte: upper nibble is the field length, lower nibble the type
+	// (0 = account key filter shown in UI, 2 = hidden).
+	i := 1
+	lt := data[i]
+	n, typ := int(lt>>4), int(lt&0x0F)
+	i++
+	if n > 0 && i+n <= len(data) {
+		f.Fields["account_key_filter"] = hexString(data[i : i+n])
+		f.Fields["filter_type"] = typ
+		i += n
+	}
+	if i < len(data) {
+		lt = data[i]
+		n = int(lt >> 4)
+		i++
+		if n > 0 && i+n <= len(data) {
+			f.Fields["salt"] = hexString(data[i : i+n])
+		}
+	}
+	f.Product = "Fast Pair accessory"
+	return f, true
+}
+
+// decodeExposureNotification parses a GAEN broadcast: a 16-byte rolling
+// proximity identifier followed by 4 bytes of encrypted metadata.
+func decodeExposureNotification(data []byte) (Frame, bool) {
+	if len(data) < 20 {
+		return Frame{}, false
+	}
+	return Frame{
+		Format:  "exposure_notification",
+		Product: "Phone (Exposure Notifications)",
+		Fields: map[string]any{
+			"rpi": hexString(data[0:16]),
+			"aem": hexString(data[16:20]),
+		},
+	}, true
+}
@@ -0,0 +1,97 @@
+package decode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompanyMicrosoft is the company ID of Microsoft.
+const CompanyMicrosoft uint16 = 0x0006
+
+// CDP device types (MS-CDP, Bluetooth advertising beacon).
+var cdpDeviceTypes = map[byte]string{
+	1:  "Xbox One",
+	6:  "Apple iPhone",
+	7:  "Apple iPad",
+	8:  "Android device",
+	9:  "Windows 10 desktop",
+	11: "Windows 10 phone",
+	12: "Linux device",
+	13: "Windows IoT",
+	14: "Surface Hub",
+	15: "Windows laptop",
+	16: "Windows tablet",
+}
+
+// decodeMicrosoft parses Microsoft manufacturer data: Connected Devices
+// Platform beacons (scenario 0x01) and Swift Pair (scenario 0x03).
+func decodeMicrosoft(_ uint16, data []byte) (Frame, bool) {
+	if len(data) < 2 {
+		return Frame{}, false
+	}
+	switch data[0] {
+	case 0x01:
+		return decodeCDPBeacon(data)
+	case 0x03:
+		return decodeSwiftPair(data)
+	}
+	return Frame{}, false
+}
+
+// CDP beacon: scenario, version+device type, version+flags, reserved,
+// salt (4) and device hash (16).
+func decodeCDPBeacon(data []byte) (Frame, bool) {
+	if len(data) < 4 {
+		return Frame{}, false
+	}
+	devType := data[1] & 0x1F
+	f := Frame{
+		Format: "microsoft_cdp",
+		Fields: map[string]any{
+			"version":     int(data[1] >> 5),
+			"device_type": int(devType),
+			"flags":       fmt.Sprintf("0x%02X", data[2]),
+		},
+	}
+	if name, ok := cdpDeviceTypes[devType]; ok {
+		f.Fields["device"] = name
+		f.Product = name
+	}
+	if len(data) >= 8 {
+		f.Fields["salt"] = hexString(data[4:8])
+	}
+	if len(data) >= 24 {
+		f.Fields["device_hash"] = hexString(data[8:24])
+	}
+	return f, true
+}
+
+// Swift Pair: scenario 0x03, sub-scenario, reserved RSSI byte, optional
+// Class of Device (sub-scenarios 0x01/0x02) and a UTF-8 display name.
+func decodeSwiftPair(data []byte) (Frame, bool) {
+	if len(data) < 3 {
+		return Frame{}, false
+	}
+	sub := data[1]
+	f := Frame{
+		Format:  "microsoft_swift_pair",
+		Product: "Swift Pair device",
+		Fields:  map[string]any{"sub_scenario": int(sub)},
+	}
+	i := 3
+	if sub == 0x01 || sub == 0x02 {
+		if len(data) < 6 {
+			return f, true
+		}
+		f.Fields["class_of_device"] = fmt.Sprintf("0x%06X", uint32(data[3])|uint32(data[4])<<8|uint32(data[5])<<16)
+		i = 6
+	}
+	if i < len(data) {
+		name := strings.TrimRight(string(data[i:]), "\x00")
+		if name != "" {
+			f.Fields["display_name"] = name
+			f.Product = name
+		}
+	}
+	return f, true
+}
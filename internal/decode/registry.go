@@ -0,0 +1,91 @@
+package decode
+
+import (
+	"sync"
+)
+
+// ManufacturerDecoder decodes a manufacturer payload (without the company ID).
+// It returns ok=false when the payload is not in its format.
+type ManufacturerDecoder func(companyID uint16, data []byte) (Frame, bool)
+
+// ServiceDataDecoder decodes a service data payload.
+// It returns ok=false when the payload is not in its format.
+type ServiceDataDecoder func(data []byte) (Frame, bool)
+
+// registry holds decoders keyed by company ID or canonical service UUID.
+// Decoders for the same key are tried in registration order; the first one
+// that accepts the payload wins.
+var registry = struct {
+	mu         sync.RWMutex
+	byCompany  map[uint16][]ManufacturerDecoder
+	anyCompany []ManufacturerDecoder
+	byService  map[string][]ServiceDataDecoder
+}{
+	byCompany: map[uint16][]ManufacturerDecoder{},
+	byService: map[string][]ServiceDataDecoder{},
+}
+
+// RegisterManufacturer adds a decoder for manufacturer data of one company ID.
+func RegisterManufacturer(companyID uint16, d ManufacturerDecoder) {
+	if d == nil {
+		return
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.byCompany[companyID] = append(registry.byCompany[companyID], d)
+}
+
+// RegisterAnyManufacturer adds a decoder that is tried for every company ID
+// before the company-specific decoders (e.g. AltBeacon).
+func RegisterAnyManufacturer(d ManufacturerDecoder) {
+	if d == nil {
+		return
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.anyCompany = append(registry.anyCompany, d)
+}
+
+// RegisterServiceData adds a decoder for service data of one service UUID
+// (16-bit or 128-bit string form). Invalid UUIDs are ignored.
+func RegisterServiceData(uuid string, d ServiceDataDecoder) {
+	u := CanonicalUUID(uuid)
+	if u == "" || d == nil {
+		return
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.byService[u] = append(registry.byService[u], d)
+}
+
+func decodeManufacturer(companyID uint16, data []byte) (Frame, bool) {
+	registry.mu.RLock()
+	anyDecs := registry.anyCompany
+	decs := registry.byCompany[companyID]
+	registry.mu.RUnlock()
+
+	for _, d := range anyDecs {
+		if f, ok := d(companyID, data); ok {
+			return f, true
+		}
+	}
+	for _, d := range decs {
+		if f, ok := d(companyID, data); ok {
+			return f, true
+		}
+	}
+	return Frame{}, false
+}
+
+func decodeServiceData(uuid128 string, data []byte) (Frame, bool) {
+	registry.mu.RLock()
+	decs := registry.byService[uuid128]
+	registry.mu.RUnlock()
+
+	for _, d := range decs {
+		if f, ok := d(data); ok {
+			return f, true
+		}
+	}
+	return Frame{}, false
+}
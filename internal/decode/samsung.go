@@ -0,0 +1,48 @@
+package decode
+
+import "bytes"
+
+// CompanySamsung is the company ID of Samsung Electronics.
+const CompanySamsung uint16 = 0x0075
+
+var (
+	// Easy Setup prefixes used by Galaxy Buds and Galaxy Watch.
+	samsungBudsPrefix  = []byte{0x42, 0x09, 0x81, 0x02, 0x14, 0x15, 0x03, 0x21, 0x01, 0x09}
+	samsungWatchPrefix = []byte{0x01, 0x00, 0x02, 0x00, 0x01, 0x01, 0xFF, 0x00, 0x00, 0x43}
+)
+
+// decodeSamsung identifies Samsung device families from manufacturer data.
+// Payloads are mostly undocumented; only the stable prefixes are used.
+func decodeSamsung(_ uint16, data []byte) (Frame, bool) {
+	if len(data) < 2 {
+		return Frame{}, false
+	}
+	f := Frame{
+		Format: "samsung",
+		Fields: map[string]any{"type": hexString(data[:1])},
+	}
+	switch {
+	case bytes.HasPrefix(data, samsungBudsPrefix) && len(data) >= len(samsungBudsPrefix)+3:
+		n := len(samsungBudsPrefix)
+		f.Fields["family"] = "buds"
+		f.Fields["model_id"] = hexString(data[n : n+3])
+		f.Product = "Samsung Galaxy Buds"
+
+	case bytes.HasPrefix(data, samsungWatchPrefix) && len(data) > len(samsungWatchPrefix):
+		f.Fields["family"] = "watch"
+		f.Fields["model_id"] = hexString(data[len(samsungWatchPrefix) : len(samsungWatchPrefix)+1])
+		f.Product = "Samsung Galaxy Watch"
+
+	case data[0] == 0x42:
+		// Galaxy phones/tablets (Quick Share, Continuity-like services).
+		f.Fields["family"] = "galaxy"
+		if len(data) >= 3 {
+			f.Fields["subtype"] = hexString(data[1:3])
+		}
+		f.Product = "Samsung Galaxy device"
+
+	default:
+		f.Fields["data"] = hexString(data)
+	}
+	return f, true
+}
@@ -0,0 +1,381 @@
+package decode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Sensor broadcast keys.
+const (
+	ServiceBTHome uint16 = 0xFCD2
+	ServiceXiaomi uint16 = 0xFE95
+
+	CompanyRuuvi uint16 = 0x0499
+	CompanyGovee uint16 = 0xEC88
+)
+
+// ---- BTHome v2 ----
+
+type bthomeObject struct {
+	metric string
+	size   int // bytes; 0 = variable (first byte is the length)
+	signed bool
+	factor float64
+	unit   string
+}
+
+// BTHome v2 object IDs (https://bthome.io/format/).
+var bthomeObjects = map[byte]bthomeObject{
+	0x00: {"packet_id", 1, false, 1, ""},
+	0x01: {"battery", 1, false, 1, "%"},
+	0x02: {"temperature", 2, true, 0.01, "°C"},
+	0x03: {"humidity", 2, false, 0.01, "%"},
+	0x04: {"pressure", 3, false, 0.01, "hPa"},
+	0x05: {"illuminance", 3, false, 0.01, "lx"},
+	0x06: {"mass", 2, false, 0.01, "kg"},
+	0x07: {"mass", 2, false, 0.01, "lb"},
+	0x08: {"dewpoint", 2, true, 0.01, "°C"},
+	0x09: {"count", 1, false, 1, ""},
+	0x0A: {"energy", 3, false, 0.001, "kWh"},
+	0x0B: {"power", 3, false, 0.01, "W"},
+	0x0C: {"voltage", 2, false, 0.001, "V"},
+	0x0D: {"pm2_5", 2, false, 1, "µg/m³"},
+	0x0E: {"pm10", 2, false, 1, "µg/m³"},
+	0x0F: {"generic_boolean", 1, false, 1, ""},
+	0x10: {"power_on", 1, false, 1, ""},
+	0x11: {"opening", 1, false, 1, ""},
+	0x12: {"co2", 2, false, 1, "ppm"},
+	0x13: {"tvoc", 2, false, 1, "µg/m³"},
+	0x14: {"moisture", 2, false, 0.01, "%"},
+	0x15: {"battery_low", 1, false, 1, ""},
+	0x16: {"battery_charging", 1, false, 1, ""},
+	0x17: {"carbon_monoxide", 1, false, 1, ""},
+	0x18: {"cold", 1, false, 1, ""},
+	0x19: {"connectivity", 1, false, 1, ""},
+	0x1A: {"door", 1, false, 1, ""},
+	0x1B: {"garage_door", 1, false, 1, ""},
+	0x1C: {"gas", 1, false, 1, ""},
+	0x1D: {"heat", 1, false, 1, ""},
+	0x1E: {"light", 1, false, 1, ""},
+	0x1F: {"lock", 1, false, 1, ""},
+	0x20: {"moisture_detected", 1, false, 1, ""},
+	0x21: {"motion", 1, false, 1, ""},
+	0x22: {"moving", 1, false, 1, ""},
+	0x23: {"occupancy", 1, false, 1, ""},
+	0x24: {"plug", 1, false, 1, ""},
+	0x25: {"presence", 1, false, 1, ""},
+	0x26: {"problem", 1, false, 1, ""},
+	0x27: {"running", 1, false, 1, ""},
+	0x28: {"safety", 1, false, 1, ""},
+	0x29: {"smoke", 1, false, 1, ""},
+	0x2A: {"sound", 1, false, 1, ""},
+	0x2B: {"tamper", 1, false, 1, ""},
+	0x2C: {"vibration", 1, false, 1, ""},
+	0x2D: {"window", 1, false, 1, ""},
+	0x2E: {"humidity", 1, false, 1, "%"},
+	0x2F: {"moisture", 1, false, 1, "%"},
+	0x3A: {"button", 1, false, 1, ""},
+	0x3C: {"dimmer", 2, false, 1, ""},
+	0x3D: {"count", 2, false, 1, ""},
+	0x3E: {"count", 4, false, 1, ""},
+	0x3F: {"rotation", 2, true, 0.1, "°"},
+	0x40: {"distance", 2, false, 1, "mm"},
+	0x41: {"distance", 2, false, 0.1, "m"},
+	0x42: {"duration", 3, false, 0.001, "s"},
+	0x43: {"current", 2, false, 0.001, "A"},
+	0x44: {"speed", 2, false, 0.01, "m/s"},
+	0x45: {"temperature", 2, true, 0.1, "°C"},
+	0x46: {"uv_index", 1, false, 0.1, ""},
+	0x47: {"volume", 2, false, 0.1, "L"},
+	0x48: {"volume", 2, false, 1, "mL"},
+	0x49: {"volume_flow_rate", 2, false, 0.001, "m³/h"},
+	0x4A: {"voltage", 2, false, 0.1, "V"},
+	0x4B: {"gas", 3, false, 0.001, "m³"},
+	0x4C: {"gas", 4, false, 0.001, "m³"},
+	0x4D: {"energy", 4, false, 0.001, "kWh"},
+	0x4E: {"volume", 4, false, 0.001, "L"},
+	0x4F: {"water", 4, false, 0.001, "L"},
+	0x50: {"timestamp", 4, false, 1, "s"},
+	0x51: {"acceleration", 2, false, 0.001, "m/s²"},
+	0x52: {"gyroscope", 2, false, 0.001, "°/s"},
+	0x53: {"text", 0, false, 0, ""},
+	0x54: {"raw", 0, false, 0, ""},
+	0x55: {"volume_storage", 4, false, 0.001, "L"},
+	0x56: {"conductivity", 2, false, 1, "µS/cm"},
+	0x57: {"temperature", 1, true, 1, "°C"},
+	0x58: {"temperature", 1, true, 0.35, "°C"},
+	0x59: {"count", 1, true, 1, ""},
+	0x5A: {"count", 2, true, 1, ""},
+	0x5B: {"count", 4, true, 1, ""},
+	0x5C: {"power", 4, true, 0.01, "W"},
+	0x5D: {"current", 2, true, 0.001, "A"},
+	0x5E: {"direction", 2, false, 0.01, "°"},
+	0x5F: {"precipitation", 2, false, 0.1, "mm"},
+	0x60: {"channel", 1, false, 1, ""},
+	0xF0: {"device_type_id", 2, false, 1, ""},
+	0xF1: {"firmware_version", 4, false, 1, ""},
+	0xF2: {"firmware_version", 3, false, 1, ""},
+}
+
+func decodeBTHome(data []byte) (Frame, bool) {
+	if len(data) < 1 {
+		return Frame{}, false
+	}
+	info := data[0]
+	version := int(info >> 5)
+	if version != 2 {
+		return Frame{}, false
+	}
+	encrypted := info&0x01 != 0
+	f := Frame{
+		Format: "bthome_v2",
+		Fields: map[string]any{
+			"encrypted": encrypted,
+			"trigger":   info&0x04 != 0,
+		},
+	}
+	if encrypted {
+		return f, true
+	}
+
+	readings := make([]Reading, 0, 4)
+	for i := 1; i < len(data); {
+		id := data[i]
+		obj, ok := bthomeObjects[id]
+		if !ok {
+			// Unknown object: sizes are implicit, so the rest cannot be parsed.
+			f.Fields["unparsed_hex"] = hexString(data[i:])
+			break
+		}
+		i++
+		if obj.size == 0 {
+			if i >= len(data) {
+				break
+			}
+			n := int(data[i])
+			i++
+			if i+n > len(data) {
+				break
+			}
+			if obj.metric == "text" {
+				f.Fields[obj.metric] = string(data[i : i+n])
+			} else {
+				f.Fields[obj.metric] = hexString(data[i : i+n])
+			}
+			i += n
+			continue
+		}
+		if i+obj.size > len(data) {
+			break
+		}
+		raw := leUint(data[i : i+obj.size])
+		i += obj.size
+		var v float64
+		if obj.signed {
+			v = float64(signExtend(raw, obj.size*8)) * obj.factor
+		} else {
+			v = float64(raw) * obj.factor
+		}
+		v = math.Round(v*1e6) / 1e6 // drop float noise from the scale factor
+		switch obj.metric {
+		case "packet_id", "device_type_id":
+			f.Fields[obj.metric] = int(raw)
+		case "firmware_version":
+			f.Fields[obj.metric] = fmt.Sprintf("0x%X", raw)
+		default:
+			readings = append(readings, Reading{Metric: obj.metric, Value: v, Unit: obj.unit})
+		}
+	}
+	return f.WithReadings(readings), true
+}
+
+// ---- Xiaomi MiBeacon ----
+
+var miBeaconProducts = map[uint16]string{
+	0x0098: "Xiaomi Mi Flora (HHCCJCY01)",
+	0x01AA: "Xiaomi Mi Temperature and Humidity Sensor (LYWSDCGQ)",
+	0x0347: "Qingping Temperature and Humidity Sensor (CGG1)",
+	0x045B: "Xiaomi Temperature and Humidity Clock (LYWSD02)",
+	0x055B: "Xiaomi Temperature and Humidity Monitor 2 (LYWSD03MMC)",
+}
+
+func decodeMiBeacon(data []byte) (Frame, bool) {
+	if len(data) < 5 {
+		return Frame{}, false
+	}
+	fc := binary.LittleEndian.Uint16(data[0:2])
+	product := binary.LittleEndian.Uint16(data[2:4])
+	encrypted := fc&0x0008 != 0
+	f := Frame{
+		Format: "xiaomi_mibeacon",
+		Fields: map[string]any{
+			"frame_control": fmt.Sprintf("0x%04X", fc),
+			"version":       int(fc >> 12),
+			"product_id":    fmt.Sprintf("0x%04X", product),
+			"frame_counter": int(data[4]),
+			"encrypted":     encrypted,
+		},
+	}
+	if name, ok := miBeaconProducts[product]; ok {
+		f.Fields["model"] = name
+		f.Product = name
+	}
+
+	i := 5
+	if fc&0x0010 != 0 { // MAC included (little-endian)
+		if i+6 > len(data) {
+			return f, true
+		}
+		mac := data[i : i+6]
+		f.Fields["mac"] = fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X", mac[5], mac[4], mac[3], mac[2], mac[1], mac[0])
+		i += 6
+	}
+	if fc&0x0020 != 0 { // capability
+		if i >= len(data) {
+			return f, true
+		}
+		capability := data[i]
+		i++
+		if capability&0x20 != 0 { // I/O capability
+			i += 2
+		}
+	}
+	if encrypted || fc&0x0040 == 0 || i >= len(data) {
+		return f, true
+	}
+
+	readings := make([]Reading, 0, 2)
+	for i+3 <= len(data) {
+		typ := binary.LittleEndian.Uint16(data[i : i+2])
+		n := int(data[i+2])
+		i += 3
+		if i+n > len(data) {
+			break
+		}
+		v := data[i : i+n]
+		i += n
+		readings = append(readings, miBeaconObject(typ, v)...)
+	}
+	return f.WithReadings(readings), true
+}
+
+func miBeaconObject(typ uint16, v []byte) []Reading {
+	switch {
+	case typ == 0x1004 && len(v) >= 2:
+		return []Reading{{"temperature", float64(int16(binary.LittleEndian.Uint16(v))) / 10, "°C"}}
+	case typ == 0x1006 && len(v) >= 2:
+		return []Reading{{"humidity", float64(binary.LittleEndian.Uint16(v)) / 10, "%"}}
+	case typ == 0x1007 && len(v) >= 3:
+		return []Reading{{"illuminance", float64(leUint(v[:3])), "lx"}}
+	case typ == 0x1008 && len(v) >= 1:
+		return []Reading{{"moisture", float64(v[0]), "%"}}
+	case typ == 0x1009 && len(v) >= 2:
+		return []Reading{{"conductivity", float64(binary.LittleEndian.Uint16(v)), "µS/cm"}}
+	case typ == 0x100A && len(v) >= 1:
+		return []Reading{{"battery", float64(v[0]), "%"}}
+	case typ == 0x100D && len(v) >= 4:
+		return []Reading{
+			{"temperature", float64(int16(binary.LittleEndian.Uint16(v[0:2]))) / 10, "°C"},
+			{"humidity", float64(binary.LittleEndian.Uint16(v[2:4])) / 10, "%"},
+		}
+	case typ == 0x1010 && len(v) >= 2:
+		return []Reading{{"formaldehyde", float64(binary.LittleEndian.Uint16(v)) / 100, "mg/m³"}}
+	case typ == 0x1017 && len(v) >= 4:
+		return []Reading{{"no_motion_time", float64(binary.LittleEndian.Uint32(v)), "s"}}
+	}
+	return nil
+}
+
+// ---- Ruuvi ----
+
+func decodeRuuvi(_ uint16, data []byte) (Frame, bool) {
+	// Data format 5 (RAWv2) only.
+	if len(data) < 24 || data[0] != 0x05 {
+		return Frame{}, false
+	}
+	f := Frame{
+		Format:  "ruuvi_rawv2",
+		Product: "RuuviTag",
+		Fields:  map[string]any{},
+	}
+	readings := make([]Reading, 0, 8)
+	if raw := binary.BigEndian.Uint16(data[1:3]); raw != 0x8000 {
+		readings = append(readings, Reading{"temperature", float64(int16(raw)) * 0.005, "°C"})
+	}
+	if raw := binary.BigEndian.Uint16(data[3:5]); raw != 0xFFFF {
+		readings = append(readings, Reading{"humidity", float64(raw) * 0.0025, "%"})
+	}
+	if raw := binary.BigEndian.Uint16(data[5:7]); raw != 0xFFFF {
+		readings = append(readings, Reading{"pressure", (float64(raw) + 50000) / 100, "hPa"})
+	}
+	for j, axis := range []string{"acceleration_x", "acceleration_y", "acceleration_z"} {
+		raw := binary.BigEndian.Uint16(data[7+2*j : 9+2*j])
+		if raw != 0x8000 {
+			readings = append(readings, Reading{axis, float64(int16(raw)) / 1000, "g"})
+		}
+	}
+	power := binary.BigEndian.Uint16(data[13:15])
+	if mv := power >> 5; mv != 0x07FF {
+		readings = append(readings, Reading{"voltage", (float64(mv) + 1600) / 1000, "V"})
+	}
+	if tx := power & 0x1F; tx != 0x1F {
+		f.Fields["tx_power"] = -40 + 2*int(tx)
+	}
+	if mc := data[15]; mc != 0xFF {
+		readings = append(readings, Reading{"movement_count", float64(mc), ""})
+	}
+	if seq := binary.BigEndian.Uint16(data[16:18]); seq != 0xFFFF {
+		f.Fields["sequence"] = int(seq)
+	}
+	mac := data[18:24]
+	f.Fields["mac"] = fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X", mac[0], mac[1], mac[2], mac[3], mac[4], mac[5])
+	return f.WithReadings(readings), true
+}
+
+// ---- Govee ----
+
+func decodeGovee(_ uint16, data []byte) (Frame, bool) {
+	switch len(data) {
+	case 6:
+		// H5072/H5075/H5101/H5102/H5177: 3-byte packed temperature+humidity.
+		packed := uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+		neg := packed&0x800000 != 0
+		packed &= 0x7FFFFF
+		temp := float64(packed/1000) / 10
+		if neg {
+			temp = -temp
+		}
+		hum := float64(packed%1000) / 10
+		f := Frame{Format: "govee_h5075", Product: "Govee H5075"}
+		return f.WithReadings([]Reading{
+			{"temperature", temp, "°C"},
+			{"humidity", hum, "%"},
+			{"battery", float64(data[4]), "%"},
+		}), true
+	case 7:
+		// H5074: little-endian temperature and humidity (0.01 units).
+		temp := float64(int16(binary.LittleEndian.Uint16(data[1:3]))) / 100
+		hum := float64(binary.LittleEndian.Uint16(data[3:5])) / 100
+		f := Frame{Format: "govee_h5074", Product: "Govee H5074"}
+		return f.WithReadings([]Reading{
+			{"temperature", temp, "°C"},
+			{"humidity", hum, "%"},
+			{"battery", float64(data[5]), "%"},
+		}), true
+	}
+	return Frame{}, false
+}
+
+func leUint(b []byte) uint64 {
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v
+}
+
+func signExtend(v uint64, bits int) int64 {
+	shift := 64 - bits
+	return int64(v<<shift) >> shift
+}
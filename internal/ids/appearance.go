@@ -0,0 +1,62 @@
+package ids
+
+import (
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type appearanceFile struct {
+	AppearanceValues []appearanceCategory `yaml:"appearance_values"`
+}
+
+type appearanceCategory struct {
+	Category    any                     `yaml:"category"`
+	Name        string                  `yaml:"name"`
+	Subcategory []appearanceSubcategory `yaml:"subcategory"`
+}
+
+type appearanceSubcategory struct {
+	Value any    `yaml:"value"`
+	Name  string `yaml:"name"`
+}
+
+// Appearance names keyed by category (bits 15..6) and by full 16-bit value.
+type appearanceNames struct {
+	categories map[uint16]string
+	values     map[uint16]string
+}
+
+// LoadAppearanceYaml loads the Bluetooth SIG appearance_values.yaml file.
+// The GAP Appearance value is (category << 6) | subcategory.
+func LoadAppearanceYaml(path string) (appearanceNames, error) {
+	out := appearanceNames{categories: map[uint16]string{}, values: map[uint16]string{}}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return out, err
+	}
+
+	var f appearanceFile
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return out, err
+	}
+
+	for _, c := range f.AppearanceValues {
+		cat, ok := parseUint16Value(c.Category)
+		name := strings.TrimSpace(c.Name)
+		if !ok || name == "" || cat > 0x3FF {
+			continue
+		}
+		out.categories[cat] = name
+		for _, sc := range c.Subcategory {
+			sub, ok := parseUint16Value(sc.Value)
+			subName := strings.TrimSpace(sc.Name)
+			if !ok || subName == "" || sub > 0x3F {
+				continue
+			}
+			out.values[cat<<6|sub] = subName
+		}
+	}
+	return out, nil
+}
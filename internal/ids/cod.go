@@ -0,0 +1,165 @@
+package ids
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ClassOfDevice is a decoded Bluetooth Class of Device (BR/EDR) value.
+type ClassOfDevice struct {
+	Major    string   `json:"major"`
+	Minor    string   `json:"minor,omitempty"`
+	Services []string `json:"services,omitempty"`
+}
+
+// String returns "Major / Minor" (or just Major when the minor class is unknown).
+func (c ClassOfDevice) String() string {
+	if c.Minor == "" {
+		return c.Major
+	}
+	return c.Major + " / " + c.Minor
+}
+
+var codMajorClasses = map[uint32]string{
+	0x00: "Miscellaneous",
+	0x01: "Computer",
+	0x02: "Phone",
+	0x03: "LAN/Network Access Point",
+	0x04: "Audio/Video",
+	0x05: "Peripheral",
+	0x06: "Imaging",
+	0x07: "Wearable",
+	0x08: "Toy",
+	0x09: "Health",
+	0x1F: "Uncategorized",
+}
+
+var codComputerMinor = []string{
+	"Uncategorized", "Desktop Workstation", "Server-class Computer", "Laptop",
+	"Handheld PC/PDA", "Palm-size PC/PDA", "Wearable Computer", "Tablet",
+}
+
+var codPhoneMinor = []string{
+	"Uncategorized", "Cellular", "Cordless", "Smartphone",
+	"Wired Modem or Voice Gateway", "Common ISDN Access",
+}
+
+var codLANMinor = []string{
+	"Fully Available", "1-17% Utilized", "17-33% Utilized", "33-50% Utilized",
+	"50-67% Utilized", "67-83% Utilized", "83-99% Utilized", "No Service Available",
+}
+
+var codAudioVideoMinor = []string{
+	"Uncategorized", "Wearable Headset", "Hands-free Device", "",
+	"Microphone", "Loudspeaker", "Headphones", "Portable Audio",
+	"Car Audio", "Set-top Box", "HiFi Audio Device", "VCR",
+	"Video Camera", "Camcorder", "Video Monitor", "Video Display and Loudspeaker",
+	"Video Conferencing", "", "Gaming/Toy",
+}
+
+var codPeripheralMinor = []string{
+	"Uncategorized", "Joystick", "Gamepad", "Remote Control",
+	"Sensing Device", "Digitizer Tablet", "Card Reader", "Digital Pen",
+	"Handheld Scanner", "Handheld Gestural Input Device",
+}
+
+var codWearableMinor = []string{
+	"", "Wristwatch", "Pager", "Jacket", "Helmet", "Glasses", "Pin",
+}
+
+var codToyMinor = []string{
+	"", "Robot", "Vehicle", "Doll/Action Figure", "Controller", "Game",
+}
+
+var codHealthMinor = []string{
+	"Undefined", "Blood Pressure Monitor", "Thermometer", "Weighing Scale",
+	"Glucose Meter", "Pulse Oximeter", "Heart/Pulse Rate Monitor", "Health Data Display",
+	"Step Counter", "Body Composition Analyzer", "Peak Flow Monitor", "Medication Monitor",
+	"Knee Prosthesis", "Ankle Prosthesis", "Generic Health Manager", "Personal Mobility Device",
+}
+
+// Service class bits 13..23.
+var codServiceClasses = []struct {
+	bit  uint
+	name string
+}{
+	{13, "Limited Discoverable Mode"},
+	{14, "LE Audio"},
+	{16, "Positioning"},
+	{17, "Networking"},
+	{18, "Rendering"},
+	{19, "Capturing"},
+	{20, "Object Transfer"},
+	{21, "Audio"},
+	{22, "Telephony"},
+	{23, "Information"},
+}
+
+// DecodeClassOfDevice splits a 24-bit Class of Device into major and minor
+// device class and service class names.
+func DecodeClassOfDevice(cod uint32) ClassOfDevice {
+	major := (cod >> 8) & 0x1F
+	minor := (cod >> 2) & 0x3F
+
+	out := ClassOfDevice{}
+	if name, ok := codMajorClasses[major]; ok {
+		out.Major = name
+	} else {
+		out.Major = fmt.Sprintf("Reserved (0x%02X)", major)
+	}
+
+	switch major {
+	case 0x01:
+		out.Minor = pickName(codComputerMinor, minor)
+	case 0x02:
+		out.Minor = pickName(codPhoneMinor, minor)
+	case 0x03:
+		out.Minor = pickName(codLANMinor, minor>>3)
+	case 0x04:
+		out.Minor = pickName(codAudioVideoMinor, minor)
+	case 0x05:
+		parts := make([]string, 0, 2)
+		switch minor >> 4 {
+		case 1:
+			parts = append(parts, "Keyboard")
+		case 2:
+			parts = append(parts, "Pointing Device")
+		case 3:
+			parts = append(parts, "Combo Keyboard/Pointing Device")
+		}
+		if sub := minor & 0x0F; sub != 0 || len(parts) == 0 {
+			if n := pickName(codPeripheralMinor, sub); n != "" {
+				parts = append(parts, n)
+			}
+		}
+		out.Minor = strings.Join(parts, ", ")
+	case 0x06:
+		parts := make([]string, 0, 4)
+		for i, n := range []string{"Display", "Camera", "Scanner", "Printer"} {
+			if minor&(1<<(2+uint(i))) != 0 {
+				parts = append(parts, n)
+			}
+		}
+		out.Minor = strings.Join(parts, ", ")
+	case 0x07:
+		out.Minor = pickName(codWearableMinor, minor)
+	case 0x08:
+		out.Minor = pickName(codToyMinor, minor)
+	case 0x09:
+		out.Minor = pickName(codHealthMinor, minor)
+	}
+
+	for _, sc := range codServiceClasses {
+		if cod&(1<<sc.bit) != 0 {
+			out.Services = append(out.Services, sc.name)
+		}
+	}
+	return out
+}
+
+func pickName(names []string, i uint32) string {
+	if int(i) < len(names) {
+		return names[i]
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package ids
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type companyFile struct {
+	CompanyIdentifiers []companyEntry `yaml:"company_identifiers"`
+}
+
+type companyEntry struct {
+	Value any    `yaml:"value"`
+	Name  string `yaml:"name"`
+}
+
+// LoadCompanyYaml loads company ID -> Name mapping from the Bluetooth SIG
+// company_identifiers.yaml file.
+func LoadCompanyYaml(path string) (map[uint16]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f companyFile
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+
+	out := make(map[uint16]string, len(f.CompanyIdentifiers))
+	for _, e := range f.CompanyIdentifiers {
+		id, ok := parseUint16Value(e.Value)
+		name := strings.TrimSpace(e.Name)
+		if !ok || name == "" {
+			continue
+		}
+		out[id] = name
+	}
+	return out, nil
+}
+
+func parseUint16Value(v any) (uint16, bool) {
+	s := strings.ToLower(normalizeUUIDValue(v))
+	if s == "" {
+		return 0, false
+	}
+	base := 10
+	if strings.HasPrefix(s, "0x") {
+		s = strings.TrimPrefix(s, "0x")
+		base = 16
+	}
+	n, err := strconv.ParseUint(s, base, 16)
+	if err != nil {
+		return 0, false
+	}
+	return uint16(n), true
+}
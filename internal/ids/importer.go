@@ -0,0 +1,504 @@
+package ids
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestName is the file recording where identifier data came from.
+// It is written to the custom directory by Import and may also be shipped in
+// the default directory.
+const ManifestName = "ids_manifest.json"
+
+// Manifest describes an installed set of identifier files.
+type Manifest struct {
+	Source      string                  `json:"source"`
+	InstalledAt string                  `json:"installed_at"`
+	Files       map[string]ManifestFile `json:"files"`
+}
+
+// ManifestFile describes one installed identifier file.
+type ManifestFile struct {
+	SHA256  string `json:"sha256"`
+	Entries int    `json:"entries"`
+	ModTime string `json:"mod_time,omitempty"`
+}
+
+// idsFile is one supported identifier file and how to flatten it into
+// key -> name entries for validation and diffing.
+type idsFile struct {
+	name string
+	load func(path string) (map[string]string, error)
+}
+
+var idsFiles = []idsFile{
+	{"oui.csv", LoadOUI},
+	{"mam.csv", LoadOUI},
+	{"oui36.csv", LoadOUI},
+	{"iab.csv", LoadOUI},
+	{"cid.csv", LoadCID},
+	{"service_uuids.yaml", LoadUUIDYaml},
+	{"characteristic_uuids.yaml", LoadUUIDYaml},
+	{"company_identifiers.yaml", func(path string) (map[string]string, error) {
+		items, err := LoadCompanyYaml(path)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]string, len(items))
+		for k, v := range items {
+			out[fmt.Sprintf("0x%04X", k)] = v
+		}
+		return out, nil
+	}},
+	{"appearance_values.yaml", func(path string) (map[string]string, error) {
+		items, err := LoadAppearanceYaml(path)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]string, len(items.categories)+len(items.values))
+		for k, v := range items.categories {
+			out[fmt.Sprintf("category:0x%03X", k)] = v
+		}
+		for k, v := range items.values {
+			out[fmt.Sprintf("0x%04X", k)] = v
+		}
+		return out, nil
+	}},
+	{"device_ids.yaml", func(path string) (map[string]string, error) {
+		items, err := LoadDeviceIDYaml(path)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]string, len(items.vendors)+len(items.products))
+		for k, v := range items.vendors {
+			out[k] = v
+		}
+		for k, v := range items.products {
+			out[k] = v
+		}
+		return out, nil
+	}},
+}
+
+// FileDiff summarizes the changes an imported file brings over the current set.
+type FileDiff struct {
+	Name    string
+	Entries int
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// ImportResult is the outcome of Import.
+type ImportResult struct {
+	Files    []FileDiff
+	Manifest Manifest
+}
+
+// ImportConfig controls Import.
+type ImportConfig struct {
+	LoadConfig
+	// Source is a directory or a .zip / .tar.gz / .tgz / .tar archive.
+	Source string
+	// DryRun validates and diffs without installing anything.
+	DryRun bool
+}
+
+// Import validates identifier files found in cfg.Source (matched by file name,
+// at any depth), diffs them against the currently loaded set and installs them
+// into the custom directory. Each file is written to a temporary name and
+// renamed into place; the manifest is written last. A failed install puts the
+// previous files back.
+func Import(cfg ImportConfig) (ImportResult, error) {
+	var res ImportResult
+	src := strings.TrimSpace(cfg.Source)
+	if src == "" {
+		return res, fmt.Errorf("no source given")
+	}
+	defaultDir, customDir := cfg.dirs()
+
+	stage, err := os.MkdirTemp("", "pible-ids-")
+	if err != nil {
+		return res, err
+	}
+	defer os.RemoveAll(stage)
+
+	found, err := collectIDSFiles(src, stage)
+	if err != nil {
+		return res, err
+	}
+	if len(found) == 0 {
+		return res, fmt.Errorf("no identifier files found in %s", src)
+	}
+
+	res.Manifest = Manifest{
+		Source:      src,
+		InstalledAt: time.Now().Format("2006-01-02 15:04:05"),
+		Files:       map[string]ManifestFile{},
+	}
+
+	for _, f := range idsFiles {
+		path, ok := found[f.name]
+		if !ok {
+			continue
+		}
+		next, err := f.load(path)
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", f.name, err)
+		}
+		if len(next) == 0 {
+			return res, fmt.Errorf("%s: no valid entries", f.name)
+		}
+
+		// Current effective entries: default overlaid by custom.
+		cur := map[string]string{}
+		for _, dir := range []string{defaultDir, customDir} {
+			if items, err := f.load(filepath.Join(dir, f.name)); err == nil {
+				for k, v := range items {
+					cur[k] = v
+				}
+			}
+		}
+		res.Files = append(res.Files, diffEntries(f.name, cur, next))
+
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return res, err
+		}
+		mf := ManifestFile{SHA256: sum, Entries: len(next)}
+		if st, err := os.Stat(path); err == nil && !st.ModTime().IsZero() {
+			mf.ModTime = st.ModTime().Format("2006-01-02 15:04:05")
+		}
+		res.Manifest.Files[f.name] = mf
+	}
+
+	if cfg.DryRun {
+		return res, nil
+	}
+
+	if err := os.MkdirAll(customDir, 0o755); err != nil {
+		return res, err
+	}
+	// Keep entries of files installed by an earlier import.
+	if prev, err := ReadManifest(customDir); err == nil && prev != nil {
+		for k, v := range prev.Files {
+			if _, ok := res.Manifest.Files[k]; !ok {
+				res.Manifest.Files[k] = v
+			}
+		}
+	}
+	b, err := json.MarshalIndent(res.Manifest, "", "  ")
+	if err != nil {
+		return res, err
+	}
+	files := make(map[string][]byte, len(res.Files)+1)
+	for _, d := range res.Files {
+		data, err := os.ReadFile(found[d.Name])
+		if err != nil {
+			return res, fmt.Errorf("install %s: %w", d.Name, err)
+		}
+		files[d.Name] = data
+	}
+	files[ManifestName] = b
+	if err := installFiles(customDir, files); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (cfg LoadConfig) dirs() (defaultDir, customDir string) {
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	defaultDir = filepath.Join(dataDir, "default")
+	customDir = cfg.CustomDir
+	if customDir == "" {
+		customDir = filepath.Join(dataDir, "custom")
+	}
+	return defaultDir, customDir
+}
+
+// ReadManifest reads the manifest in dir. It returns nil, nil when there is none.
+func ReadManifest(dir string) (*Manifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func isIDSFile(name string) bool {
+	for _, f := range idsFiles {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// collectIDSFiles returns supported file name -> local path. Archives are
+// extracted into stage.
+func collectIDSFiles(src, stage string) (map[string]string, error) {
+	st, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]string{}
+	if st.IsDir() {
+		err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && isIDSFile(info.Name()) {
+				found[info.Name()] = path
+			}
+			return nil
+		})
+		return found, err
+	}
+
+	lower := strings.ToLower(src)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		zr, err := zip.OpenReader(src)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		for _, zf := range zr.File {
+			name := filepath.Base(zf.Name)
+			if zf.FileInfo().IsDir() || !isIDSFile(name) {
+				continue
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return nil, err
+			}
+			dst := filepath.Join(stage, name)
+			err = extractTo(dst, rc, zf.Modified)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			found[name] = dst
+		}
+		return found, nil
+
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"), strings.HasSuffix(lower, ".tar"):
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		var r io.Reader = f
+		if !strings.HasSuffix(lower, ".tar") {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return nil, err
+			}
+			defer gz.Close()
+			r = gz
+		}
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			name := filepath.Base(hdr.Name)
+			if hdr.Typeflag != tar.TypeReg || !isIDSFile(name) {
+				continue
+			}
+			dst := filepath.Join(stage, name)
+			if err := extractTo(dst, tr, hdr.ModTime); err != nil {
+				return nil, err
+			}
+			found[name] = dst
+		}
+		return found, nil
+	}
+
+	// A single file.
+	name := filepath.Base(src)
+	if !isIDSFile(name) {
+		return nil, fmt.Errorf("unsupported source %s (expected a directory, archive or known identifier file)", src)
+	}
+	found[name] = src
+	return found, nil
+}
+
+func extractTo(dst string, r io.Reader, mod time.Time) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if !mod.IsZero() {
+		_ = os.Chtimes(dst, mod, mod)
+	}
+	return nil
+}
+
+// installFiles installs a set of files (name -> content) into dir. Every
+// file, and a backup copy of every file it replaces, is first written next to
+// its target; only then are the new files renamed into place. If a rename
+// fails, the files already renamed are restored from their backups (or
+// removed when they are new), so dir keeps the previous set. Readers may
+// still see a mix of old and new files while the renames run.
+func installFiles(dir string, files map[string][]byte) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		// The manifest goes last so it never describes files not yet in place.
+		if name != ManifestName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := files[ManifestName]; ok {
+		names = append(names, ManifestName)
+	}
+
+	staged := make([]string, len(names))
+	backups := make([]string, len(names)) // "" when there is no previous file
+	cleanup := func() {
+		for i := range names {
+			if staged[i] != "" {
+				os.Remove(staged[i])
+			}
+			if backups[i] != "" {
+				os.Remove(backups[i])
+			}
+		}
+	}
+	for i, name := range names {
+		tmp, err := writeTemp(dir, name, files[name])
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("install %s: %w", name, err)
+		}
+		staged[i] = tmp
+		prev, err := os.ReadFile(filepath.Join(dir, name))
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			cleanup()
+			return fmt.Errorf("back up %s: %w", name, err)
+		}
+		if backups[i], err = writeTemp(dir, name+".bak", prev); err != nil {
+			cleanup()
+			return fmt.Errorf("back up %s: %w", name, err)
+		}
+	}
+	for i, name := range names {
+		if err := os.Rename(staged[i], filepath.Join(dir, name)); err != nil {
+			// Put back what was already replaced.
+			for j := 0; j < i; j++ {
+				dst := filepath.Join(dir, names[j])
+				if backups[j] == "" {
+					os.Remove(dst)
+				} else if os.Rename(backups[j], dst) == nil {
+					backups[j] = ""
+				}
+			}
+			for j := 0; j < i; j++ {
+				staged[j] = ""
+			}
+			cleanup()
+			return fmt.Errorf("install %s: %w", name, err)
+		}
+	}
+	for _, b := range backups {
+		if b != "" {
+			os.Remove(b)
+		}
+	}
+	return nil
+}
+
+// writeTemp writes b to a hidden temporary file for name in dir and returns
+// its path.
+func writeTemp(dir, name string, b []byte) (string, error) {
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-")
+	if err != nil {
+		return "", err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return "", err
+	}
+	if err := os.Chmod(tmpName, 0o644); err != nil {
+		os.Remove(tmpName)
+		return "", err
+	}
+	return tmpName, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func diffEntries(name string, cur, next map[string]string) FileDiff {
+	d := FileDiff{Name: name, Entries: len(next)}
+	for k, v := range next {
+		old, ok := cur[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, k)
+		case old != v:
+			d.Changed = append(d.Changed, k)
+		}
+	}
+	for k := range cur {
+		if _, ok := next[k]; !ok {
+			d.Removed = append(d.Removed, k)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
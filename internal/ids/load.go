@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 type LoadConfig struct {
@@ -12,6 +14,8 @@ type LoadConfig struct {
 	// Example:
 	//   data/default/oui.csv
 	//   data/custom/oui.csv
+	// The MA-M (mam.csv), MA-S (oui36.csv), IAB (iab.csv) and CID (cid.csv)
+	// registries are loaded from the same folders when present.
 	DataDir string
 
 	// CustomDir optionally overrides the custom directory path. When empty, it is
@@ -19,47 +23,162 @@ type LoadConfig struct {
 	CustomDir string
 }
 
+// Load reads the identifier files. The resolver is returned even when no data
+// exists yet, so Reload and MaybeReload can pick up files added later (e.g. by
+// "pible ids import"); until then all lookups come back empty.
 func Load(cfg LoadConfig) (*Resolver, error) {
-	if cfg.DataDir == "" {
-		cfg.DataDir = "data"
+	data, _ := loadData(cfg)
+
+	res := &Resolver{cfg: cfg}
+	res.cur.Store(data)
+	res.stamp = dataStamp(cfg)
+	res.lastStat = time.Now()
+
+	// Validate directories existence only when user explicitly provided them.
+	if cfg.CustomDir != "" {
+		if _, err := os.Stat(cfg.CustomDir); err != nil {
+			return res, fmt.Errorf("custom-data-dir not accessible: %w", err)
+		}
+	}
+
+	return res, nil
+}
+
+// Reload re-reads all identifier files and swaps them in at once. When a
+// file exists but fails to parse the previous data is kept and the error is
+// returned.
+func (r *Resolver) Reload() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+// MaybeReload reloads the data when any identifier file changed since the
+// last load. Files are checked at most every 30 seconds. It reports whether
+// new data was swapped in.
+func (r *Resolver) MaybeReload() (bool, error) {
+	if r == nil {
+		return false, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	every := r.statEvery
+	if every <= 0 {
+		every = 30 * time.Second
+	}
+	now := time.Now()
+	if !r.lastStat.IsZero() && now.Sub(r.lastStat) < every {
+		return false, nil
+	}
+	r.lastStat = now
+
+	stamp := dataStamp(r.cfg)
+	if stamp == r.stamp {
+		return false, nil
+	}
+	if err := r.reloadLocked(); err != nil {
+		// Do not retry the same broken files on every check.
+		r.stamp = stamp
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *Resolver) reloadLocked() error {
+	stamp := dataStamp(r.cfg)
+	data, errs := loadData(r.cfg)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
-	defaultDir := filepath.Join(cfg.DataDir, "default")
-	customDir := cfg.CustomDir
-	if customDir == "" {
-		customDir = filepath.Join(cfg.DataDir, "custom")
+	if data.empty() {
+		return fmt.Errorf("no identifier data found")
 	}
+	r.cur.Store(data)
+	r.stamp = stamp
+	return nil
+}
+
+// loadData reads default files and overlays custom ones. Missing files are
+// skipped; files that exist but cannot be parsed are reported in errs.
+func loadData(cfg LoadConfig) (*resolverData, []error) {
+	defaultDir, customDir := cfg.dirs()
 
-	res := &Resolver{
+	res := &resolverData{
 		vendors:          map[string]string{},
+		cids:             map[string]string{},
 		serviceUUIDNames: map[string]string{},
 		charUUIDNames:    map[string]string{},
+		companyNames:     map[uint16]string{},
+		appearance:       appearanceNames{categories: map[uint16]string{}, values: map[uint16]string{}},
+		deviceIDs:        deviceIDs{vendors: map[string]string{}, products: map[string]string{}},
 	}
 
-	// Load defaults (best-effort).
-	_ = loadOUIInto(res.vendors, filepath.Join(defaultDir, "oui.csv"))
-	_ = loadUUIDYamlInto(res.serviceUUIDNames, filepath.Join(defaultDir, "service_uuids.yaml"))
-	_ = loadUUIDYamlInto(res.charUUIDNames, filepath.Join(defaultDir, "characteristic_uuids.yaml"))
+	var errs []error
+	check := func(path string, err error) {
+		if err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
 
-	// Overlay custom (best-effort).
-	_ = loadOUIInto(res.vendors, filepath.Join(customDir, "oui.csv"))
-	_ = loadUUIDYamlInto(res.serviceUUIDNames, filepath.Join(customDir, "service_uuids.yaml"))
-	_ = loadUUIDYamlInto(res.charUUIDNames, filepath.Join(customDir, "characteristic_uuids.yaml"))
+	// Load defaults, then overlay custom (best-effort).
+	for _, dir := range []string{defaultDir, customDir} {
+		for _, name := range ieeeRegistryFiles {
+			path := filepath.Join(dir, name)
+			check(path, loadOUIInto(res.vendors, path))
+		}
+		path := filepath.Join(dir, "cid.csv")
+		check(path, loadCIDInto(res.cids, path))
+		path = filepath.Join(dir, "service_uuids.yaml")
+		check(path, loadUUIDYamlInto(res.serviceUUIDNames, path))
+		path = filepath.Join(dir, "characteristic_uuids.yaml")
+		check(path, loadUUIDYamlInto(res.charUUIDNames, path))
+		path = filepath.Join(dir, "company_identifiers.yaml")
+		check(path, loadCompanyYamlInto(res.companyNames, path))
+		path = filepath.Join(dir, "appearance_values.yaml")
+		check(path, loadAppearanceYamlInto(&res.appearance, path))
+		path = filepath.Join(dir, "device_ids.yaml")
+		check(path, loadDeviceIDYamlInto(&res.deviceIDs, path))
+	}
 
-	// If nothing loaded at all, return nil resolver without error.
-	if len(res.vendors) == 0 && len(res.serviceUUIDNames) == 0 && len(res.charUUIDNames) == 0 {
-		return nil, nil
+	if !res.empty() {
+		res.version = describeDataVersion(defaultDir, customDir)
 	}
+	return res, errs
+}
 
-	// Validate directories existence only when user explicitly provided them.
-	if cfg.CustomDir != "" {
-		if _, err := os.Stat(cfg.CustomDir); err != nil {
-			return res, fmt.Errorf("custom-data-dir not accessible: %w", err)
+func (d *resolverData) empty() bool {
+	return len(d.vendors) == 0 && len(d.cids) == 0 && len(d.serviceUUIDNames) == 0 && len(d.charUUIDNames) == 0 && len(d.companyNames) == 0 && len(d.appearance.categories) == 0 &&
+		len(d.deviceIDs.vendors) == 0 && len(d.deviceIDs.products) == 0
+}
+
+// dataStamp fingerprints the size and modification time of every identifier
+// file (and manifest) in the default and custom folders.
+func dataStamp(cfg LoadConfig) string {
+	defaultDir, customDir := cfg.dirs()
+	var b strings.Builder
+	for _, dir := range []string{defaultDir, customDir} {
+		names := []string{ManifestName}
+		for _, f := range idsFiles {
+			names = append(names, f.name)
+		}
+		for _, name := range names {
+			st, err := os.Stat(filepath.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(&b, "%s/%s:%d:%d;", dir, name, st.Size(), st.ModTime().UnixNano())
 		}
 	}
-
-	return res, nil
+	return b.String()
 }
 
+// IEEE registry CSVs, in load order (later files win on identical prefixes).
+var ieeeRegistryFiles = []string{"oui.csv", "mam.csv", "oui36.csv", "iab.csv"}
+
 func loadOUIInto(dst map[string]string, path string) error {
 	if _, err := os.Stat(path); err != nil {
 		return err
@@ -74,6 +193,20 @@ func loadOUIInto(dst map[string]string, path string) error {
 	return nil
 }
 
+func loadCIDInto(dst map[string]string, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	items, err := LoadCID(path)
+	if err != nil {
+		return err
+	}
+	for k, v := range items {
+		dst[k] = v
+	}
+	return nil
+}
+
 func loadUUIDYamlInto(dst map[string]string, path string) error {
 	if _, err := os.Stat(path); err != nil {
 		return err
@@ -92,4 +225,72 @@ func loadUUIDYamlInto(dst map[string]string, path string) error {
 	return nil
 }
 
+func loadCompanyYamlInto(dst map[uint16]string, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	items, err := LoadCompanyYaml(path)
+	if err != nil {
+		return err
+	}
+	for k, v := range items {
+		dst[k] = v
+	}
+	return nil
+}
+
+func loadAppearanceYamlInto(dst *appearanceNames, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	items, err := LoadAppearanceYaml(path)
+	if err != nil {
+		return err
+	}
+	for k, v := range items.categories {
+		dst.categories[k] = v
+	}
+	for k, v := range items.values {
+		dst.values[k] = v
+	}
+	return nil
+}
+
+func loadDeviceIDYamlInto(dst *deviceIDs, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	items, err := LoadDeviceIDYaml(path)
+	if err != nil {
+		return err
+	}
+	for k, v := range items.vendors {
+		dst.vendors[k] = v
+	}
+	for k, v := range items.products {
+		dst.products[k] = v
+	}
+	return nil
+}
+
+// describeDataVersion summarizes the manifests of the loaded data folders.
+func describeDataVersion(defaultDir, customDir string) string {
+	parts := make([]string, 0, 2)
+	for _, d := range []struct{ label, dir string }{{"default", defaultDir}, {"custom", customDir}} {
+		m, err := ReadManifest(d.dir)
+		if err != nil {
+			parts = append(parts, fmt.Sprintf("%s: bad manifest (%v)", d.label, err))
+			continue
+		}
+		if m == nil {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s from %s (%d files)", d.label, m.InstalledAt, m.Source, len(m.Files)))
+	}
+	if len(parts) == 0 {
+		return "bundled snapshot (no manifest)"
+	}
+	return strings.Join(parts, "; ")
+}
+
 var ErrBadUUID = errors.New("bad uuid")
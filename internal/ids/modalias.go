@@ -0,0 +1,142 @@
+package ids
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Device ID vendor ID sources (Device ID profile, VendorIDSource).
+const (
+	VendorSourceBluetooth = "bluetooth" // Bluetooth SIG company identifier
+	VendorSourceUSB       = "usb"       // USB Implementer's Forum vendor ID
+)
+
+// Modalias is a parsed BlueZ/kernel modalias string such as
+// "bluetooth:v004Cp200Ed1234" or "usb:v046DpB023d0012".
+type Modalias struct {
+	Source  string
+	Vendor  uint16
+	Product uint16
+	Version *uint16
+}
+
+// ParseModalias parses the vendor ID source, vendor, product and (optional)
+// version from a modalias string. Trailing fields (USB class info) are ignored.
+func ParseModalias(s string) (Modalias, bool) {
+	s = strings.TrimSpace(s)
+	src, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return Modalias{}, false
+	}
+	src = strings.ToLower(strings.TrimSpace(src))
+	if src != VendorSourceBluetooth && src != VendorSourceUSB {
+		return Modalias{}, false
+	}
+
+	m := Modalias{Source: src}
+	fields := map[byte]uint16{}
+	for _, key := range []byte{'v', 'p', 'd'} {
+		if len(rest) < 5 || (rest[0] != key && rest[0] != key-'a'+'A') {
+			break
+		}
+		v, err := strconv.ParseUint(rest[1:5], 16, 16)
+		if err != nil {
+			break
+		}
+		fields[key] = uint16(v)
+		rest = rest[5:]
+	}
+	vendor, okV := fields['v']
+	product, okP := fields['p']
+	if !okV || !okP {
+		return Modalias{}, false
+	}
+	m.Vendor = vendor
+	m.Product = product
+	if d, ok := fields['d']; ok {
+		m.Version = &d
+	}
+	return m, true
+}
+
+// String returns the canonical modalias form.
+func (m Modalias) String() string {
+	s := fmt.Sprintf("%s:v%04Xp%04X", m.Source, m.Vendor, m.Product)
+	if m.Version != nil {
+		s += fmt.Sprintf("d%04X", *m.Version)
+	}
+	return s
+}
+
+type deviceIDFile struct {
+	Vendors  []deviceIDVendor  `yaml:"vendors"`
+	Products []deviceIDProduct `yaml:"products"`
+}
+
+type deviceIDVendor struct {
+	Source string `yaml:"source"`
+	ID     any    `yaml:"id"`
+	Name   string `yaml:"name"`
+}
+
+type deviceIDProduct struct {
+	Source  string `yaml:"source"`
+	Vendor  any    `yaml:"vendor"`
+	Product any    `yaml:"product"`
+	Name    string `yaml:"name"`
+}
+
+// deviceIDs holds vendor and product names keyed by "source:vvvv" and
+// "source:vvvv:pppp" (lower-case source, upper-case hex).
+type deviceIDs struct {
+	vendors  map[string]string
+	products map[string]string
+}
+
+func deviceIDVendorKey(source string, vendor uint16) string {
+	return fmt.Sprintf("%s:%04X", source, vendor)
+}
+
+func deviceIDProductKey(source string, vendor, product uint16) string {
+	return fmt.Sprintf("%s:%04X:%04X", source, vendor, product)
+}
+
+// LoadDeviceIDYaml loads vendor/product names for modalias decoding
+// (device_ids.yaml).
+func LoadDeviceIDYaml(path string) (deviceIDs, error) {
+	out := deviceIDs{vendors: map[string]string{}, products: map[string]string{}}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return out, err
+	}
+
+	var f deviceIDFile
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return out, err
+	}
+
+	for _, v := range f.Vendors {
+		src := strings.ToLower(strings.TrimSpace(v.Source))
+		id, ok := parseUint16Value(v.ID)
+		name := strings.TrimSpace(v.Name)
+		if src == "" || !ok || name == "" {
+			continue
+		}
+		out.vendors[deviceIDVendorKey(src, id)] = name
+	}
+	for _, p := range f.Products {
+		src := strings.ToLower(strings.TrimSpace(p.Source))
+		vid, ok1 := parseUint16Value(p.Vendor)
+		pid, ok2 := parseUint16Value(p.Product)
+		name := strings.TrimSpace(p.Name)
+		if src == "" || !ok1 || !ok2 || name == "" {
+			continue
+		}
+		out.products[deviceIDProductKey(src, vid, pid)] = name
+	}
+	return out, nil
+}
@@ -7,9 +7,31 @@ import (
 	"strings"
 )
 
-// LoadOUI loads vendor names keyed by OUI (6 hex digits, uppercase) from a CSV
-// file with IEEE format (Registry, Assignment, Organization Name, ...).
+// IEEE registry prefix lengths in hex digits: MA-L (24-bit), MA-M (28-bit),
+// MA-S / IAB (36-bit). Lookups try the longest prefix first.
+var ouiPrefixLengths = []int{9, 7, 6}
+
+// LoadOUI loads vendor names keyed by assignment prefix (6, 7 or 9 hex
+// digits, uppercase) from a CSV file with IEEE format
+// (Registry, Assignment, Organization Name, ...). This covers the MA-L
+// (oui.csv), MA-M (mam.csv), MA-S (oui36.csv) and IAB (iab.csv) registries.
+// CID rows are skipped; see LoadCID.
 func LoadOUI(path string) (map[string]string, error) {
+	return loadIEEECSV(path, func(registry string) bool {
+		return !strings.EqualFold(registry, "CID")
+	})
+}
+
+// LoadCID loads Company ID (CID) assignments from the IEEE cid.csv file.
+// CIDs live in the locally administered address space and are only used for
+// addresses that have the local bit set.
+func LoadCID(path string) (map[string]string, error) {
+	return loadIEEECSV(path, func(registry string) bool {
+		return strings.EqualFold(registry, "CID")
+	})
+}
+
+func loadIEEECSV(path string, keep func(registry string) bool) (map[string]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -36,10 +58,15 @@ func LoadOUI(path string) (map[string]string, error) {
 		if len(rec) < 3 {
 			continue
 		}
+		if !keep(strings.TrimSpace(rec[0])) {
+			continue
+		}
 		assignment := strings.ToUpper(strings.TrimSpace(rec[1]))
 		assignment = strings.ReplaceAll(assignment, "-", "")
 		assignment = strings.ReplaceAll(assignment, ":", "")
-		if len(assignment) != 6 {
+		switch len(assignment) {
+		case 6, 7, 9:
+		default:
 			continue
 		}
 		org := strings.TrimSpace(rec[2])
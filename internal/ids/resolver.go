@@ -2,64 +2,221 @@ package ids
 
 import (
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Resolver provides name lookups for various identifiers used in BLE scanning.
 //
-// - Vendor names are resolved by MAC OUI (from oui.csv).
+// - Vendor names are resolved by longest MAC prefix (oui.csv, mam.csv, oui36.csv, iab.csv, cid.csv).
 // - Service UUID names are resolved from service_uuids.yaml.
 // - Characteristic UUID names are resolved from characteristic_uuids.yaml.
+// - Company names (manufacturer data) are resolved from company_identifiers.yaml.
+// - GAP Appearance names are resolved from appearance_values.yaml.
+// - Modalias vendor/product names are resolved from device_ids.yaml.
 //
 // All UUID keys are stored in canonical 128-bit, lower-case form.
+//
+// The loaded tables can be replaced at runtime (Reload/MaybeReload). Each
+// lookup works on one consistent snapshot; a reload never exposes a
+// partially loaded set.
 type Resolver struct {
-	vendors map[string]string
+	cur atomic.Pointer[resolverData]
+	cfg LoadConfig
+
+	// Reload bookkeeping (see MaybeReload).
+	mu        sync.Mutex
+	stamp     string
+	lastStat  time.Time
+	statEvery time.Duration
+}
+
+// resolverData is one immutable set of loaded tables.
+type resolverData struct {
+	vendors map[string]string // keyed by 6/7/9 hex digit prefix
+	cids    map[string]string
 
 	serviceUUIDNames map[string]string
 	charUUIDNames    map[string]string
+
+	companyNames map[uint16]string
+	appearance   appearanceNames
+	deviceIDs    deviceIDs
+
+	version string
 }
 
-func (r *Resolver) VendorForMAC(mac string) string {
-	if r == nil || len(r.vendors) == 0 {
+// Version describes which identifier data set was loaded (from the
+// ids_manifest.json files written by "pible ids import").
+func (r *Resolver) Version() string {
+	d := r.snapshot()
+	if d == nil {
 		return ""
 	}
-	oui := macToOUI(mac)
-	if oui == "" {
+	return d.version
+}
+
+func (r *Resolver) snapshot() *resolverData {
+	if r == nil {
+		return nil
+	}
+	return r.cur.Load()
+}
+
+// MACVendor is the result of a vendor lookup by MAC address.
+type MACVendor struct {
+	Vendor string
+	// Prefix is the matched registry assignment (6, 7 or 9 hex digits).
+	Prefix string
+	// LocallyAdministered reports the U/L bit (second-least significant bit
+	// of the first octet). Random BLE addresses and CIDs have it set.
+	LocallyAdministered bool
+	// Multicast reports the I/G bit (least significant bit of the first octet).
+	Multicast bool
+}
+
+// LookupMAC resolves the vendor of a MAC address by longest prefix match.
+// Multicast addresses never resolve; locally administered addresses only
+// resolve through the CID registry.
+func (r *Resolver) LookupMAC(mac string) MACVendor {
+	d := r.snapshot()
+	hex := macToHex(mac)
+	if hex == "" {
+		return MACVendor{}
+	}
+	first := hexNibble(hex[0])<<4 | hexNibble(hex[1])
+	out := MACVendor{
+		LocallyAdministered: first&0x02 != 0,
+		Multicast:           first&0x01 != 0,
+	}
+	if d == nil || out.Multicast {
+		return out
+	}
+	if out.LocallyAdministered {
+		if v, ok := d.cids[hex[:6]]; ok {
+			out.Vendor = v
+			out.Prefix = hex[:6]
+		}
+		return out
+	}
+	for _, n := range ouiPrefixLengths {
+		if v, ok := d.vendors[hex[:n]]; ok {
+			out.Vendor = v
+			out.Prefix = hex[:n]
+			return out
+		}
+	}
+	return out
+}
+
+func (r *Resolver) VendorForMAC(mac string) string {
+	d := r.snapshot()
+	if d == nil || (len(d.vendors) == 0 && len(d.cids) == 0) {
 		return ""
 	}
-	if v, ok := r.vendors[oui]; ok {
-		return v
+	return r.LookupMAC(mac).Vendor
+}
+
+// VendorForAddress is VendorForMAC for an address of known type: random
+// (LE private or static) addresses are not IEEE assigned and never resolve.
+func (r *Resolver) VendorForAddress(mac string, random bool) string {
+	if random {
+		return ""
 	}
-	return ""
+	return r.VendorForMAC(mac)
 }
 
 func (r *Resolver) ServiceName(uuid128Lower string) string {
-	if r == nil || len(r.serviceUUIDNames) == 0 {
+	d := r.snapshot()
+	if d == nil || len(d.serviceUUIDNames) == 0 {
 		return ""
 	}
 	u := strings.ToLower(strings.TrimSpace(uuid128Lower))
 	if u == "" {
 		return ""
 	}
-	if v, ok := r.serviceUUIDNames[u]; ok {
+	if v, ok := d.serviceUUIDNames[u]; ok {
 		return v
 	}
 	return ""
 }
 
 func (r *Resolver) CharacteristicName(uuid128Lower string) string {
-	if r == nil || len(r.charUUIDNames) == 0 {
+	d := r.snapshot()
+	if d == nil || len(d.charUUIDNames) == 0 {
 		return ""
 	}
 	u := strings.ToLower(strings.TrimSpace(uuid128Lower))
 	if u == "" {
 		return ""
 	}
-	if v, ok := r.charUUIDNames[u]; ok {
+	if v, ok := d.charUUIDNames[u]; ok {
+		return v
+	}
+	return ""
+}
+
+// CompanyName returns the Bluetooth SIG company name for a manufacturer data company ID.
+func (r *Resolver) CompanyName(id uint16) string {
+	d := r.snapshot()
+	if d == nil || len(d.companyNames) == 0 {
+		return ""
+	}
+	return d.companyNames[id]
+}
+
+// AppearanceCategory returns the category name of a GAP Appearance value
+// (e.g. "Phone", "Wearable Audio Device").
+func (r *Resolver) AppearanceCategory(v uint16) string {
+	d := r.snapshot()
+	if d == nil || len(d.appearance.categories) == 0 {
+		return ""
+	}
+	return d.appearance.categories[v>>6]
+}
+
+// AppearanceName returns "Category: Subcategory" for a GAP Appearance value,
+// or just the category name when the subcategory is generic or unknown.
+func (r *Resolver) AppearanceName(v uint16) string {
+	d := r.snapshot()
+	if d == nil || len(d.appearance.categories) == 0 {
+		return ""
+	}
+	cat := d.appearance.categories[v>>6]
+	if cat == "" {
+		return ""
+	}
+	if sub := d.appearance.values[v]; sub != "" {
+		return cat + ": " + sub
+	}
+	return cat
+}
+
+// ModaliasVendor returns the vendor name for a parsed modalias.
+func (r *Resolver) ModaliasVendor(m Modalias) string {
+	d := r.snapshot()
+	if d == nil {
+		return ""
+	}
+	if v := d.deviceIDs.vendors[deviceIDVendorKey(m.Source, m.Vendor)]; v != "" {
 		return v
 	}
+	if m.Source == VendorSourceBluetooth {
+		return r.CompanyName(m.Vendor)
+	}
 	return ""
 }
 
+// ModaliasProduct returns the product name for a parsed modalias.
+func (r *Resolver) ModaliasProduct(m Modalias) string {
+	d := r.snapshot()
+	if d == nil {
+		return ""
+	}
+	return d.deviceIDs.products[deviceIDProductKey(m.Source, m.Vendor, m.Product)]
+}
+
 func (r *Resolver) AnnotateServiceUUID(uuid128Lower string) string {
 	name := r.ServiceName(uuid128Lower)
 	if name == "" {
@@ -76,7 +233,8 @@ func (r *Resolver) AnnotateCharacteristicUUID(uuid128Lower string) string {
 	return uuid128Lower + " (" + name + ")"
 }
 
-func macToOUI(mac string) string {
+// macToHex returns the 12 upper-case hex digits of a MAC address, or "".
+func macToHex(mac string) string {
 	mac = strings.TrimSpace(mac)
 	if mac == "" {
 		return ""
@@ -85,12 +243,29 @@ func macToOUI(mac string) string {
 	parts := strings.FieldsFunc(mac, func(r rune) bool {
 		return r == ':' || r == '-'
 	})
-	if len(parts) < 3 {
+	if len(parts) != 6 {
 		return ""
 	}
-	oui := strings.ToUpper(parts[0] + parts[1] + parts[2])
-	if len(oui) != 6 {
+	hex := strings.ToUpper(strings.Join(parts, ""))
+	if len(hex) != 12 {
 		return ""
 	}
-	return oui
+	for i := 0; i < len(hex); i++ {
+		if hexNibble(hex[i]) == 0xFF {
+			return ""
+		}
+	}
+	return hex
+}
+
+func hexNibble(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	}
+	return 0xFF
 }
@@ -2,6 +2,9 @@ package status
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"pible/internal/db"
@@ -12,6 +15,26 @@ import (
 type Provider struct {
 	GPS   *gps.State
 	Store *db.Store
+	Queue QueueSource
+}
+
+// QueueStats summarises the connection queue. Started, the wait times and the
+// drop counters cover the period since the previous report.
+type QueueStats struct {
+	Pending         int
+	Active          int
+	ActiveByAdapter map[string]int
+	Started         int
+	AvgWait         time.Duration
+	MaxWait         time.Duration
+	OldestWait      time.Duration // age of the oldest pending candidate
+	DroppedStale    int
+	DroppedFull     int
+}
+
+// QueueSource reports connection queue statistics.
+type QueueSource interface {
+	QueueStats() QueueStats
 }
 
 // Run prints periodic structured status lines to the console.
@@ -51,8 +74,34 @@ func printOnce(ctx context.Context, p Provider) {
 		}
 	}
 
+	// Connection queue
+	if p.Queue != nil {
+		q := p.Queue.QueueStats()
+		util.Linef("[QUEUE]", util.ColorGray, "Pending: %d, Active: %d%s, Started: %d, Wait avg/max: %s/%s, Oldest: %s, Dropped stale/full: %d/%d",
+			q.Pending, q.Active, adapterCounts(q.ActiveByAdapter), q.Started,
+			q.AvgWait.Round(100*time.Millisecond), q.MaxWait.Round(100*time.Millisecond), q.OldestWait.Round(time.Second),
+			q.DroppedStale, q.DroppedFull)
+	}
+
 	// Battery
 	if pct := util.BatteryPercent(); pct != "" {
 		util.Linef("[BATTERY]", util.ColorGray, "%s", pct)
 	}
 }
+
+// adapterCounts formats per-adapter counts, e.g. " (hci0:2 hci1:1)".
+func adapterCounts(m map[string]int) string {
+	if len(m) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s:%d", k, m[k]))
+	}
+	return " (" + strings.Join(parts, " ") + ")"
+}